@@ -0,0 +1,91 @@
+package follow
+
+import (
+	"regexp"
+	"time"
+)
+
+// Alert calls a function for lines that match a rule.
+//
+// For example, to call notify() for every line containing "FATAL", but at most
+// once a minute and not for the same message within an hour:
+//
+//	f.Alerts = append(f.Alerts, &follow.Alert{
+//		Match: regexp.MustCompile(`FATAL`),
+//		Func:  notify,
+//		Every: time.Minute,
+//		Dedup: time.Hour,
+//	})
+//
+// The same Alert can be used for several followers; Every and Dedup apply to
+// every Follower separately.
+type Alert struct {
+	// Lines must match this regexp; it's ignored if nil.
+	Match *regexp.Regexp
+
+	// Lines must match this condition; it's ignored if nil. This can be used
+	// to match on the structured data, e.g. Data.Fields from Logfmt.
+	Cond func(Data) bool
+
+	// Function to call for every matching line. This is called from the
+	// goroutine that reads the file, so it should return quickly.
+	Func func(Data)
+
+	// Call Func at most once for this period; lines that match in the
+	// meanwhile are skipped.
+	//
+	// Default is 0, which means there is no limit.
+	Every time.Duration
+
+	// Don't call Func for lines that are identical to a line seen within this
+	// period. Only the last 1,000 distinct lines are remembered, so memory use
+	// doesn't grow with the number of unique lines.
+	//
+	// Default is 0, which means lines aren't deduplicated.
+	Dedup time.Duration
+}
+
+// State for an Alert; this is kept in the Follower, so that one Alert can be
+// used for several followers.
+type alertState struct {
+	last time.Time
+	seen dedupState
+}
+
+func (a *Alert) run(s *alertState, d Data) {
+	if a.Func == nil || (a.Match == nil && a.Cond == nil) {
+		return
+	}
+	if a.Match != nil && !a.Match.Match(d.Bytes) {
+		return
+	}
+	if a.Cond != nil && !a.Cond(d) {
+		return
+	}
+
+	now := time.Now()
+	if a.Every > 0 && now.Sub(s.last) < a.Every {
+		return
+	}
+	if a.Dedup > 0 {
+		if s.seen.dup(d.Bytes, now, a.Dedup, 0) {
+			return
+		}
+	}
+
+	s.last = now
+	a.Func(d)
+}
+
+// Get the state for an Alert.
+func (f *Follower) alertState(a *Alert) *alertState {
+	s, ok := f.alerts[a]
+	if !ok {
+		if f.alerts == nil {
+			f.alerts = make(map[*Alert]*alertState)
+		}
+		s = new(alertState)
+		f.alerts[a] = s
+	}
+	return s
+}
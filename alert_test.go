@@ -0,0 +1,59 @@
+package follow
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestAlert(t *testing.T) {
+	var got []string
+	fn := func(d Data) { got = append(got, d.String()) }
+	var s *alertState
+	run := func(a *Alert, lines ...string) {
+		for _, l := range lines {
+			a.run(s, Data{Bytes: []byte(l)})
+		}
+	}
+
+	t.Run("match", func(t *testing.T) {
+		got, s = nil, new(alertState)
+		a := &Alert{Match: regexp.MustCompile(`ERR`), Func: fn}
+		run(a, "ok", "ERR one", "ok", "ERR two")
+		if want := []string{"ERR one", "ERR two"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+
+	t.Run("cond", func(t *testing.T) {
+		got, s = nil, new(alertState)
+		a := &Alert{Cond: func(d Data) bool { return len(d.Bytes) > 3 }, Func: fn}
+		run(a, "ok", "long", "x")
+		if want := []string{"long"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+
+	t.Run("every", func(t *testing.T) {
+		got, s = nil, new(alertState)
+		a := &Alert{Match: regexp.MustCompile(`ERR`), Func: fn, Every: 50 * time.Millisecond}
+		run(a, "ERR one", "ERR two")
+		time.Sleep(60 * time.Millisecond)
+		run(a, "ERR three")
+		if want := []string{"ERR one", "ERR three"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+
+	t.Run("dedup", func(t *testing.T) {
+		got, s = nil, new(alertState)
+		a := &Alert{Match: regexp.MustCompile(`ERR`), Func: fn, Dedup: 50 * time.Millisecond}
+		run(a, "ERR one", "ERR one", "ERR two", "ERR one")
+		time.Sleep(60 * time.Millisecond)
+		run(a, "ERR one")
+		if want := []string{"ERR one", "ERR two", "ERR one"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package follow
+
+import "bytes"
+
+// Remove ANSI escape sequences from l. This modifies l in place, which is okay
+// since the result is never longer than l.
+//
+// This handles CSI sequences (ESC [ ... final byte, which includes colours),
+// OSC sequences (ESC ] ... terminated by BEL or ESC \), and other ESC
+// sequences such as ESC ( B. An incomplete sequence at the end of the line is
+// removed.
+func stripANSI(l []byte) []byte {
+	i := bytes.IndexByte(l, 0x1b)
+	if i == -1 {
+		return l
+	}
+
+	out := l[:i]
+	for i < len(l) {
+		c := l[i]
+		if c != 0x1b {
+			out = append(out, c)
+			i++
+			continue
+		}
+		if i+1 >= len(l) {
+			break
+		}
+
+		switch l[i+1] {
+		case '[': // CSI: parameters and intermediates, then a final byte in 0x40–0x7e.
+			i += 2
+			for i < len(l) && (l[i] < 0x40 || l[i] > 0x7e) {
+				i++
+			}
+			i++
+		case ']': // OSC: until BEL or ST (ESC \).
+			i += 2
+			for i < len(l) {
+				if l[i] == 0x07 {
+					i++
+					break
+				}
+				if l[i] == 0x1b && i+1 < len(l) && l[i+1] == '\\' {
+					i += 2
+					break
+				}
+				i++
+			}
+		default: // Intermediates in 0x20–0x2f, then a final byte.
+			i++
+			for i < len(l) && l[i] >= 0x20 && l[i] <= 0x2f {
+				i++
+			}
+			i++
+		}
+	}
+	return out
+}
@@ -0,0 +1,55 @@
+package follow
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestStripANSI(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"plain", "plain"},
+		{"\x1b[31mred\x1b[0m", "red"},
+		{"\x1b[1;38;5;208mbold orange\x1b[m text", "bold orange text"},
+		{"a\x1b[2Kb", "ab"},
+		{"\x1b]0;title\x07after", "after"},
+		{"\x1b]8;;http://example.com\x1b\\link\x1b]8;;\x1b\\", "link"},
+		{"\x1b(Bx", "x"},
+		{"\x1b=x", "x"},
+		{"trailing\x1b", "trailing"},
+		{"trailing\x1b[31", "trailing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got := string(stripANSI([]byte(tt.in)))
+			if got != tt.want {
+				t.Errorf("\ngot:  %q\nwant: %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripANSIFollow(t *testing.T) {
+	f, tmp, data := startData(context.Background(), t, func(f *Follower) {
+		f.StripANSI = true
+		f.Severity = &Severity{Tokens: []string{"ERROR"}}
+	})
+	write(t, tmp, "\x1b[32mINFO\x1b[0m one", "\x1b[31mERROR\x1b[0m two", "ERROR three")
+	f.Stop()
+
+	var got []string
+	var offsets []int64
+	for _, d := range <-data {
+		got, offsets = append(got, d.String()), append(offsets, d.Offset)
+	}
+	if want := []string{"ERROR two", "ERROR three"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+	if want := []int64{18, 37}; !reflect.DeepEqual(offsets, want) {
+		t.Errorf("offsets: %v; want %v", offsets, want)
+	}
+}
@@ -0,0 +1,88 @@
+package follow
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff increases the time between attempts to reopen a file that went
+// away, rather than trying every second. For example, to start at one second
+// and wait at most a minute between attempts:
+//
+//	f.Retry = -1
+//	f.Backoff = &follow.Backoff{Max: time.Minute, Jitter: 0.2}
+//
+// Jitter is useful when many Followers may be retrying at once, such as after
+// a log directory was unmounted, so they don't all try at the same time.
+type Backoff struct {
+	// Time to wait after the first attempt. Default is 1s.
+	Initial time.Duration
+
+	// Multiply the wait by this after every attempt. Default is 2.
+	Factor float64
+
+	// Maximum time to wait, before Jitter is applied. Default is 0, which
+	// means there is no maximum, other than Follower.Retry.
+	Max time.Duration
+
+	// Randomly make every wait up to this fraction shorter or longer; for
+	// example 0.2 means between 80% and 120%. Default is 0, which means
+	// there is no randomness.
+	Jitter float64
+}
+
+// Time to wait after attempt n, starting at 0.
+func (b *Backoff) delay(n int, rnd func() float64) time.Duration {
+	initial, factor := b.Initial, b.Factor
+	if initial <= 0 {
+		initial = time.Second
+	}
+	if factor <= 0 {
+		factor = 2
+	}
+
+	d := float64(initial) * math.Pow(factor, float64(n))
+	if b.Max > 0 && d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+	if b.Jitter > 0 {
+		d *= 1 + b.Jitter*(2*rnd()-1)
+	}
+	if d > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(d)
+}
+
+// RetryPolicy decides how long to wait before the next attempt to reopen a
+// file that went away, or to give up. Attempt is the number of attempts so far
+// (starting at 0), not counting Follower.FastRetry, and elapsed is the time
+// since the first of them.
+//
+// For example, to try every 5 seconds for an hour:
+//
+//	f.RetryPolicy = func(attempt int, elapsed time.Duration) (time.Duration, bool) {
+//		return 5 * time.Second, elapsed > time.Hour
+//	}
+//
+// ErrCannotReopen is sent once it gives up.
+type RetryPolicy func(attempt int, elapsed time.Duration) (wait time.Duration, giveUp bool)
+
+// The RetryPolicy for Retry and Backoff.
+func (f *Follower) retryPolicy(n int, elapsed time.Duration) (time.Duration, bool) {
+	forever := f.Retry == -1
+	if !forever && elapsed >= f.Retry {
+		return 0, true
+	}
+
+	wait := f.retryInterval
+	if f.Backoff != nil {
+		wait = f.Backoff.delay(n, rand.Float64)
+	}
+	// Don't wait past Retry for the last attempt.
+	if rem := f.Retry - elapsed; !forever && wait > rem {
+		wait = rem
+	}
+	return wait, false
+}
@@ -0,0 +1,118 @@
+package follow
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		b    Backoff
+		rnd  float64
+		want []time.Duration
+	}{
+		{Backoff{}, 0, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}},
+		{Backoff{Max: 3 * time.Second}, 0, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}},
+		{Backoff{Initial: 10 * time.Millisecond, Factor: 3}, 0, []time.Duration{10 * time.Millisecond, 30 * time.Millisecond, 90 * time.Millisecond, 270 * time.Millisecond}},
+		{Backoff{Jitter: 0.5}, 0, []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second, 4 * time.Second}},
+		{Backoff{Jitter: 0.5}, 1, []time.Duration{1500 * time.Millisecond, 3 * time.Second, 6 * time.Second, 12 * time.Second}},
+		{Backoff{Jitter: 0.5, Max: 2 * time.Second}, 1, []time.Duration{1500 * time.Millisecond, 3 * time.Second, 3 * time.Second, 3 * time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%+v_%v", tt.b, tt.rnd), func(t *testing.T) {
+			var got []time.Duration
+			for n := 0; n < 4; n++ {
+				got = append(got, tt.b.delay(n, func() float64 { return tt.rnd }))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\ngot:  %v\nwant: %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("overflow", func(t *testing.T) {
+		if d := (&Backoff{}).delay(1000, nil); d <= 0 {
+			t.Errorf("delay %s", d)
+		}
+	})
+
+	t.Run("reopen", func(t *testing.T) {
+		tmp := filepath.Join(t.TempDir(), "f")
+		touch(t, tmp)
+
+		f := New()
+		f.Retry = -1
+		f.FastRetry = -1
+		f.Backoff = &Backoff{Initial: 10 * time.Millisecond}
+		f.Events = make(chan Event, 10)
+		err := f.Go(context.Background(), tmp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.stopAndDrain()
+
+		err = os.Remove(tmp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Millisecond) // Attempts at 10, 30, 70, 150ms.
+		touch(t, tmp)
+
+		var gone time.Time
+		for e := range f.Events {
+			if e.Kind == EventRemove {
+				gone = e.Time
+			}
+			if e.Kind == EventReappear {
+				if took := e.Time.Sub(gone); took < 140*time.Millisecond || took > 300*time.Millisecond {
+					t.Errorf("reopened after %s", took)
+				}
+				break
+			}
+		}
+	})
+}
+
+func TestRetryPolicy(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "f")
+	touch(t, tmp)
+
+	var (
+		attempts []int
+		last     time.Duration
+	)
+	f := New()
+	f.Retry = 0 // Ignored.
+	f.FastRetry = -1
+	f.RetryPolicy = func(n int, elapsed time.Duration) (time.Duration, bool) {
+		if elapsed < last {
+			t.Errorf("elapsed went from %s to %s", last, elapsed)
+		}
+		attempts, last = append(attempts, n), elapsed
+		return 5 * time.Millisecond, n == 3
+	}
+	err := f.Go(context.Background(), tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Remove(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := <-f.Data; d.Err != ErrCannotReopen {
+		t.Errorf("got %v; want ErrCannotReopen", d.Err)
+	}
+	f.stopAndDrain()
+	if want := []int{0, 1, 2, 3}; !reflect.DeepEqual(attempts, want) {
+		t.Errorf("attempts %v; want %v", attempts, want)
+	}
+	if last < 15*time.Millisecond {
+		t.Errorf("elapsed %s", last)
+	}
+}
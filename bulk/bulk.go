@@ -0,0 +1,478 @@
+// Package bulk sends followed lines to Elasticsearch or OpenSearch with the
+// _bulk API.
+package bulk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"zgo.at/follow"
+)
+
+// DefaultIndex is the default index template, which uses a new index every
+// day, e.g. "logs-2024.05.01".
+var DefaultIndex = template.Must(template.New("").Funcs(Funcs).Parse(
+	`logs-{{(time .).Format "2006.01.02"}}`))
+
+// Funcs are the functions available in index templates.
+//
+//	time   Timestamp for the line; see Timestamp.
+var Funcs = template.FuncMap{
+	"time": Timestamp,
+}
+
+// Timestamp gets the time for a line: Record.Timestamp if Follower.Parser set
+// it, or the time it was read otherwise. It's always in UTC.
+func Timestamp(d follow.Data) time.Time {
+	if !d.Timestamp.IsZero() {
+		return d.Timestamp.UTC()
+	}
+	return d.ReadAt.UTC()
+}
+
+// DefaultDoc is the default document for a line, which has the Record.Fields
+// and:
+//
+//	@timestamp   Timestamp for the line.
+//	message      The line, or Record.Message.
+//	file         File name.
+//	stream       Record.Stream, if set.
+//	trace_id     Data.TraceID, if set.
+func DefaultDoc(d follow.Data) interface{} {
+	doc := make(map[string]interface{}, len(d.Fields)+5)
+	for k, v := range d.Fields {
+		doc[k] = v
+	}
+	doc["@timestamp"] = Timestamp(d).Format(time.RFC3339Nano)
+	doc["message"] = d.String()
+	doc["file"] = d.Name
+	if d.Stream != "" {
+		doc["stream"] = d.Stream
+	}
+	if d.TraceID != "" {
+		doc["trace_id"] = d.TraceID
+	}
+	return doc
+}
+
+// Bulk indexes lines in Elasticsearch or OpenSearch.
+//
+// Fields that are zero use their default, except Retries, where 0 means
+// documents are never retried. New sets all the defaults.
+type Bulk struct {
+	// Base URL of the cluster, e.g. "http://localhost:9200"; "/_bulk" is
+	// added to it.
+	URL string
+
+	// Template for the index name; this gets a follow.Data, so labels from
+	// Record.Fields can be used with e.g. {{index .Fields "service"}}. Use
+	// template.New("").Funcs(bulk.Funcs) to get the "time" function.
+	//
+	// Default is DefaultIndex.
+	Index *template.Template
+
+	// Get the document to index for a line; this is encoded as JSON.
+	//
+	// Default is DefaultDoc.
+	Doc func(follow.Data) interface{}
+
+	// Headers to add to every request, such as Authorization; the
+	// Content-Type is always application/x-ndjson.
+	Header http.Header
+
+	// Number of times to retry a document after a 429 or 5xx response or an
+	// error, waiting RetryWait between the first attempts and doubling it
+	// every time. Documents rejected for other reasons, such as a mapping
+	// error, are never retried.
+	//
+	// Default is 5 retries, starting at 1 second.
+	Retries   int
+	RetryWait time.Duration
+
+	// Keep at most this many documents in Run while waiting to retry; the
+	// oldest are dropped if more lines are read while the cluster is
+	// unavailable.
+	//
+	// Default is 10,000.
+	QueueSize int
+
+	// Send at most this many documents in one request in Run.
+	//
+	// Default is 500.
+	BatchSize int
+
+	// Wait for at most this long before sending a batch in Run.
+	//
+	// Default is 1 second.
+	BatchWait time.Duration
+
+	// Report documents that were dropped in Run, after a failed request or
+	// because the queue is full.
+	//
+	// Default is nil, which means errors are logged with the standard log
+	// package.
+	OnError func(error)
+
+	// Client to use; default is http.DefaultClient.
+	Client *http.Client
+}
+
+// New creates a new bulk indexer with the default settings.
+func New(url string) *Bulk {
+	return &Bulk{
+		URL:       url,
+		Index:     DefaultIndex,
+		Doc:       DefaultDoc,
+		Header:    make(http.Header),
+		Retries:   5,
+		RetryWait: time.Second,
+		QueueSize: 10_000,
+		BatchSize: 500,
+		BatchWait: time.Second,
+		Client:    http.DefaultClient,
+	}
+}
+
+// Copy of b with the defaults for fields that are zero, so a Bulk that wasn't
+// created with New works too.
+func (b *Bulk) withDefaults() *Bulk {
+	c := *b
+	if c.Index == nil {
+		c.Index = DefaultIndex
+	}
+	if c.Doc == nil {
+		c.Doc = DefaultDoc
+	}
+	if c.RetryWait <= 0 {
+		c.RetryWait = time.Second
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = 10_000
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 500
+	}
+	if c.BatchWait <= 0 {
+		c.BatchWait = time.Second
+	}
+	if c.Client == nil {
+		c.Client = http.DefaultClient
+	}
+	return &c
+}
+
+// A document with its action line, ready to send.
+type item struct {
+	line  []byte
+	tries int
+}
+
+// Encode lines as actions and documents.
+func (b *Bulk) encode(lines []follow.Data) ([]item, error) {
+	items := make([]item, 0, len(lines))
+	for _, d := range lines {
+		if d.Batch != nil {
+			for _, l := range d.Batch {
+				d := d
+				d.Bytes, d.Batch = l, nil
+				it, err := b.encodeOne(d)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, it)
+			}
+			continue
+		}
+		it, err := b.encodeOne(d)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, it)
+	}
+	return items, nil
+}
+
+func (b *Bulk) encodeOne(d follow.Data) (item, error) {
+	index := new(strings.Builder)
+	err := b.Index.Execute(index, d)
+	if err != nil {
+		return item{}, err
+	}
+	action, err := json.Marshal(map[string]map[string]string{"create": {"_index": index.String()}})
+	if err != nil {
+		return item{}, err
+	}
+	doc, err := json.Marshal(b.Doc(d))
+	if err != nil {
+		return item{}, err
+	}
+
+	line := make([]byte, 0, len(action)+len(doc)+2)
+	line = append(append(line, action...), '\n')
+	line = append(append(line, doc...), '\n')
+	return item{line: line}, nil
+}
+
+// Send lines, retrying documents that failed with a 429 or 5xx.
+func (b *Bulk) Send(ctx context.Context, lines ...follow.Data) error {
+	b = b.withDefaults()
+	items, err := b.encode(lines)
+	if err != nil {
+		return fmt.Errorf("bulk.Send: %w", err)
+	}
+
+	var (
+		first error
+		wait  = b.RetryWait
+	)
+	for len(items) > 0 {
+		var errs []error
+		items, errs = b.attempt(ctx, items)
+		if first == nil && len(errs) > 0 {
+			first = errs[0]
+		}
+		if len(items) == 0 {
+			break
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return fmt.Errorf("bulk.Send: %w", ctx.Err())
+		case <-t.C:
+		}
+		wait *= 2
+	}
+	if first != nil {
+		return fmt.Errorf("bulk.Send: %w", first)
+	}
+	return nil
+}
+
+// Post items once, returning the items to try again and errors for the items
+// that were dropped: those rejected by the cluster, and those that were
+// already tried Retries times.
+func (b *Bulk) attempt(ctx context.Context, items []item) ([]item, []error) {
+	retry, rejected, err := b.post(ctx, items)
+	var errs []error
+	if rejected != nil {
+		errs = append(errs, rejected)
+	}
+
+	keep := retry[:0]
+	for _, it := range retry {
+		it.tries++
+		if it.tries > b.Retries {
+			continue
+		}
+		keep = append(keep, it)
+	}
+	if n := len(retry) - len(keep); n > 0 {
+		errs = append(errs, fmt.Errorf("dropped %d documents after %d retries: %w", n, b.Retries, err))
+	}
+	return keep, errs
+}
+
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+// Post items to the _bulk endpoint, returning the items that should be retried:
+// all of them if the request failed with an error, 429, or 5xx, or the items
+// the cluster rejected with a 429. The rejected error is for items that were
+// rejected for another reason, and err is why the others should be retried.
+func (b *Bulk) post(ctx context.Context, items []item) (retry []item, rejected, err error) {
+	body := new(bytes.Buffer)
+	for _, it := range items {
+		body.Write(it.line)
+	}
+
+	url := strings.TrimSuffix(b.URL, "/") + "/_bulk"
+	r, err := http.NewRequestWithContext(ctx, "POST", url, body)
+	if err != nil {
+		return nil, err, nil
+	}
+	for k, v := range b.Header {
+		r.Header[k] = v
+	}
+	r.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := b.Client.Do(r)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err, nil
+		}
+		return append([]item(nil), items...), nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		io.Copy(io.Discard, resp.Body)
+		err := fmt.Errorf("%s: %s", url, resp.Status)
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return append([]item(nil), items...), nil, err
+		}
+		return nil, err, nil
+	}
+
+	var br bulkResponse
+	err = json.NewDecoder(resp.Body).Decode(&br)
+	io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: reading response: %w", url, err), nil
+	}
+	if !br.Errors {
+		return nil, nil, nil
+	}
+
+	var (
+		n      int
+		reason json.RawMessage
+	)
+	for i, res := range br.Items {
+		if i >= len(items) {
+			break
+		}
+		for _, r := range res { // Only one key: the action.
+			switch {
+			case r.Status == http.StatusTooManyRequests:
+				retry = append(retry, items[i])
+			case r.Status >= 300:
+				if n == 0 {
+					reason = r.Error
+				}
+				n++
+			}
+		}
+	}
+	err = fmt.Errorf("%s: %d documents got %s", url, len(retry), http.StatusText(http.StatusTooManyRequests))
+	if n > 0 {
+		return retry, fmt.Errorf("%s: %d documents rejected; first error: %s", url, n, reason), err
+	}
+	return retry, nil, err
+}
+
+func (b *Bulk) error(err error) {
+	if b.OnError != nil {
+		b.OnError(err)
+	} else {
+		log.Print("bulk: ", err)
+	}
+}
+
+// Run indexes all lines from data in batches, until io.EOF is received, the
+// channel is closed, or the context is cancelled.
+//
+// Data with an error other than io.EOF is skipped. Documents that fail with a
+// 429 or 5xx are queued and retried with backoff, while lines are still read
+// from data; errors for documents that are dropped are sent to OnError. Once
+// data is done, this waits until everything in the queue is sent or dropped.
+//
+// This returns ctx.Err() if the context is cancelled, or an error if a line
+// can't be encoded.
+func (b *Bulk) Run(ctx context.Context, data <-chan follow.Data) error {
+	b = b.withDefaults()
+	var (
+		queue   []item
+		dropped int
+		backoff bool
+		wait    = b.RetryWait
+		t       = time.NewTimer(b.BatchWait)
+	)
+	defer t.Stop()
+	reset := func(d time.Duration) {
+		if !t.Stop() {
+			select {
+			case <-t.C:
+			default:
+			}
+		}
+		t.Reset(d)
+	}
+
+	flush := func() {
+		if dropped > 0 {
+			b.error(fmt.Errorf("dropped %d documents because the queue is full", dropped))
+			dropped = 0
+		}
+		for len(queue) > 0 {
+			n := len(queue)
+			if n > b.BatchSize {
+				n = b.BatchSize
+			}
+			retry, errs := b.attempt(ctx, queue[:n])
+			for _, err := range errs {
+				b.error(err)
+			}
+			queue = append(retry, queue[n:]...)
+			if len(retry) > 0 {
+				backoff = true
+				reset(wait)
+				wait *= 2
+				return
+			}
+			if ctx.Err() != nil {
+				return
+			}
+		}
+		backoff, wait = false, b.RetryWait
+		reset(b.BatchWait)
+	}
+
+	for data != nil {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			flush()
+		case d, ok := <-data:
+			if !ok || d.Err == io.EOF {
+				data = nil
+				break
+			}
+			if d.Err != nil {
+				continue
+			}
+			items, err := b.encode([]follow.Data{d})
+			if err != nil {
+				return fmt.Errorf("bulk.Run: %w", err)
+			}
+			queue = append(queue, items...)
+			if over := len(queue) - b.QueueSize; over > 0 {
+				queue = queue[over:]
+				dropped += over
+			}
+			if !backoff && len(queue) >= b.BatchSize {
+				flush()
+			}
+		}
+	}
+
+	for {
+		if !backoff {
+			flush()
+		}
+		if len(queue) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			backoff = false
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package bulk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"text/template"
+	"time"
+
+	"zgo.at/follow"
+)
+
+// Read the documents from a _bulk request, as "index message".
+func docs(t *testing.T, r *http.Request) []string {
+	t.Helper()
+	var (
+		dec  = json.NewDecoder(r.Body)
+		docs []string
+	)
+	for dec.More() {
+		var (
+			action map[string]map[string]string
+			doc    map[string]interface{}
+		)
+		if err := dec.Decode(&action); err != nil {
+			t.Error(err)
+			return docs
+		}
+		if err := dec.Decode(&doc); err != nil {
+			t.Error(err)
+			return docs
+		}
+		docs = append(docs, fmt.Sprintf("%s %s", action["create"]["_index"], doc["message"]))
+	}
+	return docs
+}
+
+// Respond with a status for every document.
+func respond(w http.ResponseWriter, status ...int) {
+	var items []string
+	for _, s := range status {
+		items = append(items, fmt.Sprintf(`{"create": {"status": %d, "error": {"type": "x"}}}`, s))
+	}
+	fmt.Fprintf(w, `{"errors": true, "items": [%s]}`, strings.Join(items, ", "))
+}
+
+func TestSend(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		got [][]string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path != "/_bulk" {
+			t.Errorf("wrong path: %q", r.URL.Path)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+			t.Errorf("wrong content-type: %q", ct)
+		}
+		if h := r.Header.Get("Authorization"); h != "ApiKey x" {
+			t.Errorf("wrong header: %q", h)
+		}
+		d := docs(t, r)
+		got = append(got, d)
+		switch len(got) {
+		case 1:
+			respond(w, 201, 429, 400)
+		default:
+			fmt.Fprint(w, `{"errors": false, "items": [{"create": {"status": 201}}]}`)
+		}
+	}))
+	defer srv.Close()
+
+	b := New(srv.URL + "/")
+	b.Header.Set("Authorization", "ApiKey x")
+	b.RetryWait = time.Millisecond
+	b.Index = template.Must(template.New("").Funcs(Funcs).Parse(
+		`{{index .Fields "app"}}-{{(time .).Format "2006.01"}}`))
+
+	ts := time.Date(2024, 5, 1, 14, 32, 0, 0, time.UTC)
+	err := b.Send(context.Background(),
+		follow.Data{Bytes: []byte("one"), Record: follow.Record{Timestamp: ts, Fields: map[string]string{"app": "a"}}},
+		follow.Data{Bytes: []byte("two"), Record: follow.Record{Timestamp: ts, Fields: map[string]string{"app": "b"}}},
+		follow.Data{Bytes: []byte("three"), ReadAt: ts.AddDate(0, 1, 0)})
+	if err == nil || !strings.Contains(err.Error(), "1 documents rejected") {
+		t.Errorf("wrong error: %v", err)
+	}
+
+	want := `[[a-2024.05 one b-2024.05 two -2024.06 three] [b-2024.05 two]]`
+	if fmt.Sprint(got) != want {
+		t.Errorf("\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+func TestSendRetry(t *testing.T) {
+	n := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		if n == 1 {
+			w.WriteHeader(503)
+			return
+		}
+		fmt.Fprint(w, `{"errors": false}`)
+	}))
+	defer srv.Close()
+
+	b := New(srv.URL)
+	b.RetryWait = time.Millisecond
+	err := b.Send(context.Background(), follow.Data{Bytes: []byte("x")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("sent %d requests", n)
+	}
+}
+
+func TestSendNoRetry(t *testing.T) {
+	n := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		w.WriteHeader(400)
+	}))
+	defer srv.Close()
+
+	b := New(srv.URL)
+	b.RetryWait = time.Millisecond
+	err := b.Send(context.Background(), follow.Data{Bytes: []byte("x")})
+	if err == nil {
+		t.Fatal("err is nil")
+	}
+	if n != 1 {
+		t.Errorf("sent %d requests", n)
+	}
+}
+
+func TestRun(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		got []string
+		n   int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		n++
+		if n == 1 {
+			w.WriteHeader(429)
+			return
+		}
+		for _, d := range docs(t, r) {
+			got = append(got, strings.Fields(d)[1])
+		}
+		fmt.Fprint(w, `{"errors": false}`)
+	}))
+	defer srv.Close()
+
+	b := New(srv.URL)
+	b.BatchSize = 2
+	b.RetryWait = time.Millisecond
+	b.OnError = func(err error) { t.Error(err) }
+
+	data := make(chan follow.Data)
+	go func() {
+		for _, l := range []string{"1", "2", "3"} {
+			data <- follow.Data{Bytes: []byte(l)}
+		}
+		data <- follow.Data{Err: io.EOF}
+	}()
+
+	err := b.Run(context.Background(), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := "[1 2 3]"; fmt.Sprint(got) != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
+
+// Documents are dropped once they were retried Retries times, or if the queue
+// is full.
+func TestRunDrop(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		sent int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		sent += len(docs(t, r))
+		w.WriteHeader(503)
+	}))
+	defer srv.Close()
+
+	var errs []string
+	b := New(srv.URL)
+	b.BatchSize = 2
+	b.QueueSize = 3
+	b.Retries = 1
+	b.RetryWait = 50 * time.Millisecond
+	b.OnError = func(err error) { errs = append(errs, err.Error()) }
+
+	data := make(chan follow.Data, 10)
+	for _, l := range []string{"1", "2", "3", "4", "5"} {
+		data <- follow.Data{Bytes: []byte(l)}
+	}
+	close(data)
+
+	err := b.Run(context.Background(), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 1 and 2 are sent right away, and are dropped from the queue while
+	// waiting to retry them as 4 and 5 are read. 3 and 4 are sent twice, and
+	// then 5 is sent twice.
+	mu.Lock()
+	defer mu.Unlock()
+	if sent != 8 {
+		t.Errorf("sent %d documents", sent)
+	}
+	want := []string{
+		"dropped 2 documents because the queue is full",
+		"dropped 2 documents after 1 retries: " + srv.URL + "/_bulk: 503 Service Unavailable",
+		"dropped 1 documents after 1 retries: " + srv.URL + "/_bulk: 503 Service Unavailable",
+	}
+	if fmt.Sprint(errs) != fmt.Sprint(want) {
+		t.Errorf("\ngot:  %q\nwant: %q", errs, want)
+	}
+}
+
+// A Bulk that wasn't created with New uses the defaults.
+func TestZero(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		got []string
+		n   int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		n++
+		got = append(got, docs(t, r)...)
+		fmt.Fprint(w, `{"errors": false}`)
+	}))
+	defer srv.Close()
+
+	b := &Bulk{URL: srv.URL}
+	ts := time.Date(2024, 5, 1, 14, 32, 0, 0, time.UTC)
+	err := b.Send(context.Background(), follow.Data{Bytes: []byte("one"), Record: follow.Record{Timestamp: ts}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := make(chan follow.Data, 10)
+	for _, l := range []string{"two", "three"} {
+		data <- follow.Data{Bytes: []byte(l), Record: follow.Record{Timestamp: ts}}
+	}
+	close(data)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = b.Run(ctx, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := "[logs-2024.05.01 one logs-2024.05.01 two logs-2024.05.01 three]"
+	if fmt.Sprint(got) != want || n != 2 {
+		t.Errorf("%d requests\ngot:  %s\nwant: %s", n, got, want)
+	}
+}
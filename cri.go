@@ -0,0 +1,34 @@
+package follow
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// CRI parses the CRI log format used by Kubernetes, setting the Message,
+// Stream, and Timestamp. Partial lines are joined.
+//
+//	2016-10-06T00:17:09.669794202Z stdout F log message
+type CRI struct{}
+
+func (CRI) Parse(l []byte) (Record, error) {
+	parts := bytes.SplitN(l, []byte{' '}, 4)
+	if len(parts) < 3 || len(parts[2]) == 0 {
+		return Record{}, fmt.Errorf("follow: decoding CRI log: invalid line %q", l)
+	}
+	ts, err := time.Parse(time.RFC3339Nano, string(parts[0]))
+	if err != nil {
+		return Record{}, fmt.Errorf("follow: decoding CRI log: %w", err)
+	}
+
+	rec := Record{Message: []byte{}, Stream: string(parts[1]), Timestamp: ts}
+	if len(parts) == 4 {
+		rec.Message = parts[3]
+	}
+
+	// The tag is P for a partial line or F for a full line, possibly followed
+	// by more tags separated with ":".
+	rec.Partial = parts[2][0] == 'P'
+	return rec, nil
+}
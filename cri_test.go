@@ -0,0 +1,34 @@
+package follow
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCRI(t *testing.T) {
+	f, tmp, data := startData(context.Background(), t, func(f *Follower) {
+		f.Parser = CRI{}
+	})
+	write(t, tmp,
+		`2024-01-02T03:04:05.123456789Z stdout F hello world`,
+		`2024-01-02T03:04:06Z stderr P first part, `,
+		`2024-01-02T03:04:07Z stderr P second part, `,
+		`2024-01-02T03:04:08Z stderr F last part`,
+		`2024-01-02T03:04:09+01:00 stdout F`)
+
+	f.Stop()
+	got := clean(<-data)
+	want := []Data{
+		{Bytes: []byte("hello world"), Record: Record{Stream: "stdout", Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC)}},
+		{Bytes: []byte("first part, second part, last part"), Record: Record{Stream: "stderr", Timestamp: time.Date(2024, 1, 2, 3, 4, 6, 0, time.UTC)}},
+		{Bytes: []byte{}, Record: Record{Stream: "stdout", Timestamp: time.Date(2024, 1, 2, 2, 4, 9, 0, time.UTC)}},
+	}
+	for i := range got {
+		got[i].Timestamp = got[i].Timestamp.UTC()
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
@@ -0,0 +1,37 @@
+package follow
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+)
+
+// Add a line to the current CSV record, returning the full record once all
+// quoted fields are terminated.
+//
+// Note: callers should lock!
+func (f *Follower) csvRecord(line Data) (Data, bool) {
+	if f.record != nil {
+		f.record.Bytes = append(append(f.record.Bytes, '\n'), line.Bytes...)
+		line = *f.record
+	}
+
+	// An odd number of quotes means a quoted field continues on the next line;
+	// escaped quotes ("") don't change this.
+	if bytes.Count(line.Bytes, []byte{'"'})%2 == 1 && f.keep(line) {
+		return Data{}, false
+	}
+	f.record = nil
+	return line, true
+}
+
+func parseCSV(rec []byte, comma rune) ([]string, error) {
+	r := csv.NewReader(bytes.NewReader(rec))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	cols, err := r.Read()
+	if err == io.EOF { // Empty line.
+		return nil, nil
+	}
+	return cols, err
+}
@@ -0,0 +1,29 @@
+package follow
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestCSV(t *testing.T) {
+	f, tmp, data := startData(context.Background(), t, func(f *Follower) {
+		f.CSV = ','
+	})
+	write(t, tmp, `a,b,c`, `1,"multi`)
+	write(t, tmp, `line",3`, `"with ""quotes""",x`)
+
+	f.Stop()
+	var got [][]string
+	for _, d := range <-data {
+		got = append(got, d.Columns)
+	}
+	want := [][]string{
+		{"a", "b", "c"},
+		{"1", "multi\nline", "3"},
+		{`with "quotes"`, "x"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+}
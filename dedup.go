@@ -0,0 +1,84 @@
+package follow
+
+import (
+	"hash/maphash"
+	"sync/atomic"
+	"time"
+)
+
+// Dedup drops lines that are exact duplicates of a line that was recently
+// sent, for example because something upstream wrote the same line twice.
+//
+// Lines are compared by a hash of their content; only the last Size hashes are
+// remembered, for at most Window.
+//
+//	f.Dedup = &follow.Dedup{Window: time.Minute}
+//
+// The same Dedup can be used for several followers, e.g. in a Group; every
+// Follower remembers its own lines, and Suppressed counts all of them.
+type Dedup struct {
+	// Remember lines for this long; default is 0, which means they're
+	// remembered until there are more than Size lines.
+	Window time.Duration
+
+	// Remember at most this many lines; default is 1,000.
+	Size int
+
+	suppressed int64
+}
+
+// Lines remembered for Dedup; this is kept in the Follower, so that one Dedup
+// can be used for several followers.
+type dedupState struct {
+	seed  maphash.Seed
+	seen  map[uint64]time.Time
+	order []uint64 // Hashes in seen, oldest first.
+}
+
+// Suppressed returns the number of lines that were dropped as duplicates.
+func (d *Dedup) Suppressed() int64 { return atomic.LoadInt64(&d.suppressed) }
+
+// Report if this line was seen before, and remember it if it wasn't.
+func (d *Dedup) dup(s *dedupState, l []byte, now time.Time) bool {
+	if !s.dup(l, now, d.Window, d.Size) {
+		return false
+	}
+	atomic.AddInt64(&d.suppressed, 1)
+	return true
+}
+
+// Report if this line was seen within window, and remember it if it wasn't.
+// Only the last size lines are remembered; default is 1,000.
+func (s *dedupState) dup(l []byte, now time.Time, window time.Duration, size int) bool {
+	if s.seen == nil {
+		s.seed = maphash.MakeSeed()
+		s.seen = make(map[uint64]time.Time)
+	}
+	for window > 0 && len(s.order) > 0 && now.Sub(s.seen[s.order[0]]) >= window {
+		s.forget()
+	}
+
+	var mh maphash.Hash
+	mh.SetSeed(s.seed)
+	mh.Write(l)
+	h := mh.Sum64()
+	if _, ok := s.seen[h]; ok {
+		return true
+	}
+
+	if size <= 0 {
+		size = 1000
+	}
+	for len(s.order) >= size {
+		s.forget()
+	}
+	s.seen[h] = now
+	s.order = append(s.order, h)
+	return false
+}
+
+// Forget the oldest line.
+func (s *dedupState) forget() {
+	delete(s.seen, s.order[0])
+	s.order = s.order[1:]
+}
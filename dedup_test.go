@@ -0,0 +1,63 @@
+package follow
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDedup(t *testing.T) {
+	t.Run("size", func(t *testing.T) {
+		var (
+			d   = &Dedup{Size: 2}
+			s   dedupState
+			now = time.Now()
+		)
+		var got []bool
+		for _, l := range []string{"a", "a", "b", "a", "c", "a", "a"} {
+			got = append(got, d.dup(&s, []byte(l), now))
+		}
+		want := []bool{false, true, false, true, false, false, true}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("\ngot:  %v\nwant: %v", got, want)
+		}
+		if s := d.Suppressed(); s != 3 {
+			t.Errorf("suppressed: %d", s)
+		}
+	})
+
+	t.Run("window", func(t *testing.T) {
+		var (
+			d   = &Dedup{Window: time.Second}
+			s   dedupState
+			now = time.Now()
+		)
+		var got []bool
+		for _, at := range []time.Duration{0, 500 * time.Millisecond, 1500 * time.Millisecond} {
+			got = append(got, d.dup(&s, []byte("a"), now.Add(at)))
+		}
+		want := []bool{false, true, false}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("\ngot:  %v\nwant: %v", got, want)
+		}
+	})
+
+	t.Run("follow", func(t *testing.T) {
+		dedup := &Dedup{}
+		f, tmp, lines := startWith(context.Background(), t, func(f *Follower) {
+			f.Dedup = dedup
+		})
+		write(t, tmp, "one", "one", "two", "one")
+
+		f.Stop()
+		got := <-lines
+		want := []string{"one", "two"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("\ngot:  %q\nwant: %q", got, want)
+		}
+		if s := dedup.Suppressed(); s != 2 {
+			t.Errorf("suppressed: %d", s)
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package follow
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Docker parses Docker's json-file log format, setting the Message, Stream,
+// and Timestamp. Lines that Docker split in chunks of 16K are joined.
+type Docker struct{}
+
+type dockerLine struct {
+	Log    string    `json:"log"`
+	Stream string    `json:"stream"`
+	Time   time.Time `json:"time"`
+}
+
+func (Docker) Parse(l []byte) (Record, error) {
+	var line dockerLine
+	err := json.Unmarshal(l, &line)
+	if err != nil {
+		return Record{}, fmt.Errorf("follow: decoding Docker log: %w", err)
+	}
+
+	// Docker splits lines longer than 16K in several entries; all but the last
+	// entry don't end with a newline.
+	rec := Record{Message: []byte(line.Log), Stream: line.Stream, Timestamp: line.Time}
+	if n := len(rec.Message); n > 0 && rec.Message[n-1] == '\n' {
+		rec.Message = rec.Message[:n-1]
+	} else {
+		rec.Partial = true
+	}
+	return rec, nil
+}
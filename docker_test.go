@@ -0,0 +1,28 @@
+package follow
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDocker(t *testing.T) {
+	f, tmp, data := startData(context.Background(), t, func(f *Follower) {
+		f.Parser = Docker{}
+	})
+	write(t, tmp,
+		`{"log":"hello\n","stream":"stdout","time":"2024-01-02T03:04:05.123456789Z"}`,
+		`{"log":"first part, ","stream":"stderr","time":"2024-01-02T03:04:06Z"}`,
+		`{"log":"second part\n","stream":"stderr","time":"2024-01-02T03:04:07Z"}`)
+
+	f.Stop()
+	got := clean(<-data)
+	want := []Data{
+		{Bytes: []byte("hello"), Record: Record{Stream: "stdout", Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC)}},
+		{Bytes: []byte("first part, second part"), Record: Record{Stream: "stderr", Timestamp: time.Date(2024, 1, 2, 3, 4, 6, 0, time.UTC)}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
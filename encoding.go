@@ -0,0 +1,56 @@
+package follow
+
+import "unicode/utf8"
+
+// Decoder decodes text in another encoding to UTF-8.
+//
+// This has the same method set as transform.Transformer from
+// golang.org/x/text, so you can use any decoder from there:
+//
+//	f.Encoding = unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewDecoder()
+//	f.Encoding = charmap.Windows1252.NewDecoder()
+//
+// Transform is never called with atEOF set, as a followed file never really
+// ends. Incomplete sequences at the end of the data are kept until the next
+// write.
+type Decoder interface {
+	Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error)
+	Reset()
+}
+
+// Decode data with f.Encoding.
+//
+// Note: callers should lock!
+func (f *Follower) decode(src []byte) ([]byte, error) {
+	if len(f.undecoded) > 0 {
+		src = append(f.undecoded, src...)
+		f.undecoded = nil
+	}
+
+	var (
+		// Decoding to UTF-8 grows the data by at most 3 times: a single byte
+		// can become U+FFFD.
+		dst = make([]byte, 3*len(src)+utf8.UTFMax)
+		out = make([]byte, 0, len(src))
+	)
+	for len(src) > 0 {
+		nDst, nSrc, err := f.Encoding.Transform(dst, src, false)
+		out, src = append(out, dst[:nDst]...), src[nSrc:]
+		if nDst > 0 || nSrc > 0 {
+			continue
+		}
+		if err == nil {
+			break
+		}
+
+		// Can't make any progress: either the rest is an incomplete sequence
+		// (transform.ErrShortSrc) which we want to read again later, or the
+		// data is invalid.
+		if len(src) < utf8.UTFMax {
+			f.undecoded = append([]byte(nil), src...)
+			break
+		}
+		return out, err
+	}
+	return out, nil
+}
@@ -0,0 +1,64 @@
+package follow
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Minimal UTF-16LE decoder, so we don't need to depend on x/text.
+type utf16le struct{}
+
+func (utf16le) Reset() {}
+func (utf16le) Transform(dst, src []byte, atEOF bool) (int, int, error) {
+	var nDst, nSrc int
+	for nSrc+1 < len(src) {
+		u := []uint16{uint16(src[nSrc]) | uint16(src[nSrc+1])<<8}
+		w := 2
+		if utf16.IsSurrogate(rune(u[0])) {
+			if nSrc+3 >= len(src) {
+				break
+			}
+			u, w = append(u, uint16(src[nSrc+2])|uint16(src[nSrc+3])<<8), 4
+		}
+		r := utf16.Decode(u)[0]
+		if nDst+utf8.RuneLen(r) > len(dst) {
+			return nDst, nSrc, errors.New("short dst")
+		}
+		nDst += utf8.EncodeRune(dst[nDst:], r)
+		nSrc += w
+	}
+	if nSrc < len(src) {
+		return nDst, nSrc, errors.New("short src")
+	}
+	return nDst, nSrc, nil
+}
+
+func encodeUTF16LE(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(u), byte(u>>8))
+	}
+	return b
+}
+
+func TestEncoding(t *testing.T) {
+	f, tmp, lines := startWith(context.Background(), t, func(f *Follower) {
+		f.Encoding = utf16le{}
+	})
+
+	// Write in two parts, splitting an encoded character.
+	enc := encodeUTF16LE("Hello\nwörld 🙂\n")
+	appendString(t, tmp, string(enc[:17]))
+	appendString(t, tmp, string(enc[17:]))
+
+	f.Stop()
+	got := <-lines
+	want := []string{"Hello", "wörld 🙂"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+}
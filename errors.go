@@ -0,0 +1,94 @@
+package follow
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"runtime"
+	"syscall"
+)
+
+// Errors sent on the Data (or Errors) channel, and set in Event.Err.
+var (
+	// File was removed or renamed and Retry is 0.
+	ErrFileGone = errors.New("follow: file went away")
+
+	// File was removed or renamed and couldn't be reopened within Retry.
+	ErrCannotReopen = errors.New("follow: file went away and can't reopen")
+
+	// File was truncated; this is only set in Event.Err, and not sent on
+	// Data, as reading just continues from the start of the file.
+	ErrTruncated = errors.New("follow: file truncated")
+
+	// The file is a directory. Start returns this, and it's sent once on Data
+	// if the file is replaced by a directory while waiting for it to
+	// reappear; it keeps waiting for a regular file until Retry runs out.
+	ErrIsDir = errors.New("follow: path is a directory")
+
+	// The filesystem watcher lost events because too many happened at once.
+	// The file is read again after this, so no data should be lost, but
+	// rotations or truncations may have been missed.
+	ErrWatcherOverflow = errors.New("follow: too many filesystem events; some were lost")
+
+	// Line contains invalid UTF-8 and InvalidUTF8 is UTF8Error; the line is
+	// still sent with this in Data.Err.
+	ErrInvalidUTF8 = errors.New("follow: invalid UTF-8")
+)
+
+// Kinds of WatchError, for use with errors.Is.
+var (
+	ErrTooManyWatches  = errors.New("too many watches")
+	ErrTooManyWatchers = errors.New("too many watchers")
+	ErrWatchPermission = errors.New("permission denied")
+	ErrWatchGone       = errors.New("directory doesn't exist or was unmounted")
+)
+
+// WatchError is an error from setting up or running the filesystem watcher.
+//
+// It matches one of the ErrTooManyWatches, ErrTooManyWatchers,
+// ErrWatchPermission, or ErrWatchGone errors with errors.Is if it's a known
+// error, and Hint has a suggestion how to fix it.
+type WatchError struct {
+	Dir  string // Directory that's watched.
+	Kind error  // ErrTooManyWatches, etc.; nil if unknown.
+	Hint string // How to fix it; may be blank.
+	Err  error  // Original error.
+}
+
+func (e *WatchError) Error() string {
+	if e.Kind == nil {
+		return fmt.Sprintf("follow: watching %q: %s", e.Dir, e.Err)
+	}
+	return fmt.Sprintf("follow: watching %q: %s: %s", e.Dir, e.Kind, e.Err)
+}
+
+func (e *WatchError) Unwrap() error        { return e.Err }
+func (e *WatchError) Is(target error) bool { return e.Kind != nil && target == e.Kind }
+
+func watchError(dir string, err error) error {
+	if err == nil {
+		return nil
+	}
+	e := &WatchError{Dir: dir, Err: err}
+	switch {
+	case errors.Is(err, syscall.ENOSPC):
+		e.Kind = ErrTooManyWatches
+		if runtime.GOOS == "linux" {
+			e.Hint = "raise the limit with: sysctl fs.inotify.max_user_watches=524288"
+		}
+	case errors.Is(err, syscall.EMFILE):
+		e.Kind = ErrTooManyWatchers
+		if runtime.GOOS == "linux" {
+			e.Hint = "raise the limit with: sysctl fs.inotify.max_user_instances=1024"
+		} else {
+			e.Hint = "raise the open file limit with: ulimit -n"
+		}
+	case errors.Is(err, fs.ErrPermission):
+		e.Kind = ErrWatchPermission
+		e.Hint = "make sure the directory is readable by this user"
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, syscall.ENODEV):
+		e.Kind = ErrWatchGone
+		e.Hint = "make sure the directory exists and the filesystem is mounted"
+	}
+	return e
+}
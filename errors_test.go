@@ -0,0 +1,45 @@
+package follow
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"syscall"
+	"testing"
+)
+
+func TestWatchError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want error
+	}{
+		{syscall.ENOSPC, ErrTooManyWatches},
+		{fmt.Errorf("wrapped: %w", syscall.EMFILE), ErrTooManyWatchers},
+		{fs.ErrPermission, ErrWatchPermission},
+		{&fs.PathError{Op: "watch", Path: "/x", Err: syscall.ENOENT}, ErrWatchGone},
+		{errors.New("unknown"), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v", tt.err), func(t *testing.T) {
+			err := watchError("/x", tt.err)
+
+			var we *WatchError
+			if !errors.As(err, &we) {
+				t.Fatalf("not a WatchError: %#v", err)
+			}
+			if we.Kind != tt.want {
+				t.Errorf("kind %v; want %v", we.Kind, tt.want)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("errors.Is(%v) is false", tt.want)
+			}
+			if tt.want != nil && we.Hint == "" {
+				t.Error("no hint")
+			}
+			if !errors.Is(err, tt.err) {
+				t.Error("doesn't unwrap to original error")
+			}
+		})
+	}
+}
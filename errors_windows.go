@@ -0,0 +1,20 @@
+package follow
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+// Report if the file can't be opened because another process has it locked, or
+// because it was deleted while a writer still has it open ("delete pending").
+//
+// Opening a file that's pending delete fails with ERROR_ACCESS_DENIED; this is
+// only used for files we could open before, so it's almost certainly that and
+// not a permission problem.
+func busy(err error) bool {
+	return errors.Is(err, windows.ERROR_SHARING_VIOLATION) ||
+		errors.Is(err, windows.ERROR_LOCK_VIOLATION) ||
+		errors.Is(err, windows.ERROR_DELETE_PENDING) ||
+		errors.Is(err, windows.ERROR_ACCESS_DENIED)
+}
@@ -0,0 +1,169 @@
+package follow
+
+import "time"
+
+// Event is a lifecycle event, sent on Follower.Events.
+type Event struct {
+	Kind   EventKind
+	Name   string    // File name, as passed to Start.
+	Offset int64     // Offset in the file after the event, or of the line for EventLong.
+	Time   time.Time // Time the event happened.
+
+	// ErrTruncated for EventTruncate, and ErrFileGone for EventRemove and
+	// EventRotate. For EventWaiting it's the error from the last attempt to
+	// reopen the file, such as fs.ErrNotExist or ErrIsDir.
+	Err error
+
+	// Number of attempts to reopen the file so far and the time since it went
+	// missing, for EventWaiting.
+	Attempt int
+	Waited  time.Duration
+
+	// Number of bytes skipped for EventReappear, if Follower.ReopenAt is
+	// ReopenEnd or the file is older than Follower.MaxAge.
+	Skipped int64
+
+	// Number of lines dropped for EventDrop.
+	Dropped int64
+
+	// New path of the file for EventRotate, if known; this is only set if
+	// Follower.Watch is WatchFile on Linux.
+	RenamedTo string
+}
+
+// EventKind is the kind of lifecycle event.
+type EventKind uint8
+
+// Lifecycle events.
+const (
+	EventOpen     EventKind = iota + 1 // File was opened in Start.
+	EventReopen                        // File was reopened after a signal on Reopen.
+	EventTruncate                      // File was truncated; reading starts at 0 again.
+	EventRemove                        // File was removed.
+	EventRotate                        // File was renamed, such as by logrotate.
+	EventReappear                      // File was opened again after being removed or renamed.
+	EventWaiting                       // Still waiting for the file to reappear; sent every 10 seconds.
+	EventStorm                         // File is rotated too often; waiting for Storm.Cooldown.
+	EventDrop                          // Lines were dropped because Data was full; see Follower.Overflow.
+	EventIdle                          // Nothing was read for Follower.Idle.
+	EventCaughtUp                      // Existing contents were read, if Follower.FromStart is set.
+	EventLong                          // Line was longer than Follower.MaxLineLen.
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventOpen:
+		return "open"
+	case EventReopen:
+		return "reopen"
+	case EventTruncate:
+		return "truncate"
+	case EventRemove:
+		return "remove"
+	case EventRotate:
+		return "rotate"
+	case EventReappear:
+		return "reappear"
+	case EventWaiting:
+		return "waiting"
+	case EventStorm:
+		return "storm"
+	case EventDrop:
+		return "drop"
+	case EventIdle:
+		return "idle"
+	case EventCaughtUp:
+		return "caught-up"
+	case EventLong:
+		return "long"
+	}
+	return "unknown"
+}
+
+// Queue an event, which is sent with sendEvents.
+//
+// Events are queued since they often happen while fpMu is locked, and we don't
+// want to block Stop() on a slow receiver or callback.
+func (f *Follower) event(k EventKind) {
+	f.countEvent(k)
+	if k != EventIdle && k != EventWaiting {
+		f.resetIdleStop()
+	}
+	switch k {
+	case EventReappear:
+		f.info(k.String(), "offset", f.offset, "skipped", f.skipped)
+	case EventDrop:
+		f.info(k.String(), "offset", f.offset, "dropped", f.dropping)
+	case EventRotate:
+		if f.renamedTo != "" {
+			f.info(k.String(), "offset", f.offset, "renamed_to", f.renamedTo)
+		} else {
+			f.info(k.String(), "offset", f.offset)
+		}
+	case EventWaiting:
+		f.info(k.String(), "attempt", f.attempt, "waited", time.Since(f.goneAt), "err", f.attemptErr)
+	case EventLong:
+		f.info(k.String(), "offset", f.longAt)
+	default:
+		f.info(k.String(), "offset", f.offset)
+	}
+	if f.Events == nil && f.OnOpen == nil && f.OnRotate == nil && f.OnTruncate == nil && f.OnIdle == nil {
+		return
+	}
+	f.events = append(f.events, f.newEvent(k))
+}
+
+func (f *Follower) newEvent(k EventKind) Event {
+	e := Event{Kind: k, Name: f.name, Offset: f.offset, Time: time.Now()}
+	switch k {
+	case EventReappear:
+		e.Skipped = f.skipped
+	case EventDrop:
+		e.Dropped = f.dropping
+	case EventWaiting:
+		e.Attempt, e.Waited, e.Err = f.attempt, e.Time.Sub(f.goneAt), f.attemptErr
+	case EventTruncate:
+		e.Err = ErrTruncated
+	case EventRemove:
+		e.Err = ErrFileGone
+	case EventRotate:
+		e.Err, e.RenamedTo = ErrFileGone, f.renamedTo
+	case EventLong:
+		e.Offset = f.longAt
+	}
+	return e
+}
+
+func (f *Follower) sendEvents() {
+	if len(f.events) == 0 {
+		return
+	}
+	for _, e := range f.events {
+		f.sendEvent(e)
+	}
+	f.events = f.events[:0]
+}
+
+func (f *Follower) sendEvent(e Event) {
+	var hook func(Event)
+	switch e.Kind {
+	case EventOpen, EventReopen, EventReappear:
+		hook = f.OnOpen
+	case EventRotate, EventRemove:
+		hook = f.OnRotate
+	case EventTruncate:
+		hook = f.OnTruncate
+	case EventIdle:
+		hook = f.OnIdle
+	}
+	if hook != nil {
+		hook(e)
+	}
+
+	if f.Events != nil {
+		select {
+		case f.Events <- e:
+		case <-f.done:
+		}
+	}
+}
@@ -0,0 +1,499 @@
+package follow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEvents(t *testing.T) {
+	var events chan Event
+	f, tmp, lines := startWith(context.Background(), t, func(f *Follower) {
+		events = make(chan Event, 10)
+		f.Events = events
+	})
+
+	write(t, tmp, "before")
+	err := os.Truncate(tmp, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	write(t, tmp, "truncated")
+
+	err = os.Rename(tmp, tmp+".1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	touch(t, tmp)
+	time.Sleep(50 * time.Millisecond)
+	write(t, tmp, "rotated")
+
+	err = os.Remove(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	touch(t, tmp)
+	time.Sleep(50 * time.Millisecond)
+
+	f.Reopen <- os.Interrupt
+	time.Sleep(10 * time.Millisecond)
+
+	f.Stop()
+	<-lines
+
+	var got []EventKind
+	for len(events) > 0 {
+		e := <-events
+		if e.Name != tmp {
+			t.Errorf("wrong name: %q", e.Name)
+		}
+		if e.Kind == EventTruncate && !errors.Is(e.Err, ErrTruncated) {
+			t.Errorf("wrong error for truncate: %v", e.Err)
+		}
+		got = append(got, e.Kind)
+	}
+	want := []EventKind{EventOpen, EventTruncate, EventRotate, EventReappear,
+		EventRemove, EventReappear, EventReopen}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %v\nwant: %v", got, want)
+	}
+}
+
+func TestReopenFile(t *testing.T) {
+	var events chan Event
+	f, tmp, lines := startWith(context.Background(), t, func(f *Follower) {
+		events = make(chan Event, 10)
+		f.Events = events
+		f.Retry, f.FastRetry = -1, -1
+		f.retryInterval = time.Second
+	})
+
+	if err := f.ReopenFile(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Handled in the retry loop, rather than the main loop.
+	err := os.Remove(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := f.ReopenFile(); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("wrong error while gone: %v", err)
+	}
+	touch(t, tmp)
+	if err := f.ReopenFile(); err != nil {
+		t.Errorf("error after touch: %v", err)
+	}
+	write(t, tmp, "line")
+	time.Sleep(10 * time.Millisecond)
+
+	f.Stop()
+	if err := f.ReopenFile(); err == nil {
+		t.Error("no error after Stop")
+	}
+	if got := <-lines; !reflect.DeepEqual(got, []string{"line"}) {
+		t.Errorf("lines: %q", got)
+	}
+
+	var got []EventKind
+	for len(events) > 0 {
+		got = append(got, (<-events).Kind)
+	}
+	want := []EventKind{EventOpen, EventReopen, EventRemove, EventReappear}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %v\nwant: %v", got, want)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		got []string
+	)
+	hook := func(name string) func(Event) {
+		return func(e Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, name+" "+e.Kind.String())
+		}
+	}
+
+	tmp := filepath.Join(t.TempDir(), "f")
+	touch(t, tmp)
+
+	f := New()
+	f.InvalidUTF8 = UTF8Error
+	f.OnOpen, f.OnRotate, f.OnTruncate = hook("OnOpen"), hook("OnRotate"), hook("OnTruncate")
+	f.OnError = func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !errors.Is(err, ErrInvalidUTF8) {
+			t.Errorf("wrong error: %v", err)
+		}
+		got = append(got, "OnError invalid UTF-8")
+	}
+	stop := f.Stop
+	go f.Start(context.Background(), tmp)
+	<-f.Ready
+
+	go func() {
+		for d := range f.Data {
+			if d.Err == io.EOF {
+				return
+			}
+		}
+	}()
+
+	write(t, tmp, "bad\xff")
+	err := os.Truncate(tmp, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	err = os.Rename(tmp, tmp+".1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	touch(t, tmp)
+	time.Sleep(50 * time.Millisecond)
+	stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"OnOpen open", "OnError invalid UTF-8", "OnTruncate truncate",
+		"OnRotate rotate", "OnOpen reappear"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestReopenAt(t *testing.T) {
+	for name, at := range map[string]ReopenAt{"start": ReopenStart, "end": ReopenEnd} {
+		t.Run(name, func(t *testing.T) {
+			var events chan Event
+			f, tmp, lines := startWith(context.Background(), t, func(f *Follower) {
+				f.ReopenAt = at
+				events = make(chan Event, 10)
+				f.Events = events
+			})
+			write(t, tmp, "before")
+
+			err := os.WriteFile(tmp+".new", []byte("written before reopen\n"), 0666)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = os.Remove(tmp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = os.Rename(tmp+".new", tmp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(50 * time.Millisecond)
+			write(t, tmp, "after")
+
+			f.Stop()
+			got := <-lines
+			want := []string{"before", "written before reopen", "after"}
+			var skipped int64
+			if at == ReopenEnd {
+				want = []string{"before", "after"}
+				skipped = 22
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("\ngot:  %q\nwant: %q", got, want)
+			}
+
+			for len(events) > 0 {
+				if e := <-events; e.Kind == EventReappear && e.Skipped != skipped {
+					t.Errorf("skipped %d bytes; want %d", e.Skipped, skipped)
+				}
+			}
+		})
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	t.Run("timestamp", func(t *testing.T) {
+		f, tmp, lines := startWith(context.Background(), t, func(f *Follower) {
+			f.Parser = CRI{}
+			f.MaxAge = time.Hour
+		})
+		write(t, tmp,
+			time.Now().Add(-2*time.Hour).Format(time.RFC3339Nano)+" stdout F old",
+			time.Now().Format(time.RFC3339Nano)+" stdout F new")
+
+		f.Stop()
+		got := <-lines
+		if !reflect.DeepEqual(got, []string{"new"}) {
+			t.Errorf("wrong lines: %q", got)
+		}
+	})
+
+	t.Run("mtime", func(t *testing.T) {
+		f, tmp, lines := startWith(context.Background(), t, func(f *Follower) {
+			f.MaxAge = time.Hour
+		})
+
+		err := os.WriteFile(tmp+".new", []byte("old\n"), 0666)
+		if err != nil {
+			t.Fatal(err)
+		}
+		old := time.Now().Add(-2 * time.Hour)
+		err = os.Chtimes(tmp+".new", old, old)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.Remove(tmp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.Rename(tmp+".new", tmp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(50 * time.Millisecond)
+		write(t, tmp, "new")
+
+		f.Stop()
+		got := <-lines
+		if !reflect.DeepEqual(got, []string{"new"}) {
+			t.Errorf("wrong lines: %q", got)
+		}
+	})
+
+	t.Run("mtime from start", func(t *testing.T) {
+		for _, age := range []time.Duration{time.Hour, 30 * 24 * time.Hour} {
+			tmp := filepath.Join(t.TempDir(), "f")
+			err := os.WriteFile(tmp, []byte("one\ntwo\n"), 0666)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mtime := time.Now().Add(-age)
+			err = os.Chtimes(tmp, mtime, mtime)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f := New()
+			f.FromStart, f.NoFollow, f.MaxAge = true, true, 24*time.Hour
+			f.Data = make(chan Data, 10)
+			err = f.Start(context.Background(), tmp)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for d := range f.Data {
+				if d.Err == io.EOF {
+					break
+				}
+				got = append(got, d.String())
+			}
+			want := []string{"one", "two"}
+			if age > f.MaxAge {
+				want = nil
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("age %s: wrong lines: %q", age, got)
+			}
+		}
+	})
+}
+
+// EventLong is sent once for every long line, also if it's split or read in
+// parts, and Data.Long is set on a Batch with a long line.
+func TestEventLong(t *testing.T) {
+	f, tmp, data := startData(context.Background(), t, func(f *Follower) {
+		f.MaxLineLen = 10
+		f.LongLines = LongSplit
+		f.Batch = 10
+		f.Events = make(chan Event, 20)
+	})
+	write(t, tmp, "short", strings.Repeat("x", 25), "after")
+	appendString(t, tmp, strings.Repeat("y", 15))
+	write(t, tmp, "yyy")
+	write(t, tmp, "end")
+
+	f.Stop()
+	var long []string
+	for _, d := range <-data {
+		long = append(long, fmt.Sprintf("%s %t", d.Batch[0], d.Long))
+	}
+	want := []string{"short true", "yyyyyyyyyy true", "yyyyyyyy true", "end false"}
+	if !reflect.DeepEqual(long, want) {
+		t.Errorf("\ngot:  %q\nwant: %q", long, want)
+	}
+
+	var offsets []int64
+	for len(f.Events) > 0 {
+		if e := <-f.Events; e.Kind == EventLong {
+			if e.Name != tmp {
+				t.Errorf("wrong name: %q", e.Name)
+			}
+			offsets = append(offsets, e.Offset)
+		}
+	}
+	if want := []int64{6, 38}; !reflect.DeepEqual(offsets, want) {
+		t.Errorf("offsets: %v; want %v", offsets, want)
+	}
+}
+
+func TestDropEvent(t *testing.T) {
+	f := New()
+	f.Data = make(chan Data, 1)
+	f.Overflow = OverflowDropNewest
+	f.Events = make(chan Event, 10)
+
+	for _, l := range []string{"1", "2", "3"} {
+		f.send(Data{Bytes: []byte(l)})
+	}
+	f.sendEvents()
+	if len(f.Events) != 0 {
+		t.Fatalf("event sent while still dropping: %v", <-f.Events)
+	}
+
+	<-f.Data
+	f.send(Data{Bytes: []byte("4")})
+	f.sendEvents()
+	e := <-f.Events
+	if e.Kind != EventDrop || e.Dropped != 2 {
+		t.Errorf("wrong event: %s with Dropped %d", e.Kind, e.Dropped)
+	}
+	if d := (<-f.Data).String(); d != "4" {
+		t.Errorf("wrong line: %q", d)
+	}
+}
+
+func TestIdle(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		idle int
+	)
+	f, tmp, lines := startWith(context.Background(), t, func(f *Follower) {
+		f.Idle = 50 * time.Millisecond
+		f.OnIdle = func(e Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			if e.Kind != EventIdle {
+				t.Errorf("wrong kind: %s", e.Kind)
+			}
+			idle++
+		}
+	})
+
+	// Keep writing for longer than Idle.
+	for i := 0; i < 5; i++ {
+		write(t, tmp, "line")
+	}
+	mu.Lock()
+	if idle != 0 {
+		t.Errorf("%d idle events while writing", idle)
+	}
+	mu.Unlock()
+
+	time.Sleep(160 * time.Millisecond)
+	f.Stop()
+	<-lines
+
+	mu.Lock()
+	defer mu.Unlock()
+	if idle < 2 || idle > 4 {
+		t.Errorf("%d idle events; want 3", idle)
+	}
+}
+
+func TestIdleStop(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "f")
+	touch(t, tmp)
+
+	f := New()
+	f.Data = make(chan Data, 100)
+	f.IdleStop = 50 * time.Millisecond
+	start := time.Now()
+	err := f.Go(context.Background(), tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 8; i++ {
+		write(t, tmp, "line")
+	}
+	select {
+	case <-f.Done():
+		t.Fatal("stopped while writing")
+	default:
+	}
+
+	select {
+	case <-f.Done():
+	case <-time.After(time.Second):
+		t.Fatal("didn't stop")
+	}
+	if err := f.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if took := time.Since(start); took < 100*time.Millisecond {
+		t.Errorf("stopped after %s", took)
+	}
+	if n := len(f.Data); n != 9 { // 8 lines and EOF
+		t.Errorf("%d in Data; want 9", n)
+	}
+}
+
+func TestFromStart(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "f")
+	err := os.WriteFile(tmp, []byte("one\ntwo\nthree\n"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := New()
+	f.Data = make(chan Data, 100)
+	f.Events = make(chan Event, 10)
+	f.FromStart = true
+	f.ReadSize = 5
+	err = f.Go(context.Background(), tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var kinds []EventKind
+	for i := 0; i < 2; i++ {
+		select {
+		case e := <-f.Events:
+			kinds = append(kinds, e.Kind)
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	}
+	if want := []EventKind{EventOpen, EventCaughtUp}; !reflect.DeepEqual(kinds, want) {
+		t.Errorf("events: %s; want %s", kinds, want)
+	}
+
+	write(t, tmp, "four")
+	f.Stop()
+
+	var got []string
+	for d := range f.Data {
+		if d.Err == io.EOF {
+			break
+		}
+		got = append(got, d.String())
+	}
+	if want := []string{"one", "two", "three", "four"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+}
@@ -0,0 +1,23 @@
+// Package expvar publishes the Stats of a Follower with the standard library's
+// expvar package, so they show up in /debug/vars.
+package expvar
+
+import (
+	"expvar"
+
+	"zgo.at/follow"
+)
+
+// Publish the Stats of f under name; for example:
+//
+//	f := follow.New()
+//	expvar.Publish("follow_app_log", &f)
+//
+// Will show something like this in /debug/vars:
+//
+//	"follow_app_log": {"bytes": 4096, "lines": 42, "errors": 0, ...}
+//
+// This panics if name is already used, as expvar.Publish does.
+func Publish(name string, f *follow.Follower) {
+	expvar.Publish(name, expvar.Func(func() interface{} { return f.Stats() }))
+}
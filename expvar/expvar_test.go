@@ -0,0 +1,23 @@
+package expvar
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"zgo.at/follow"
+)
+
+func TestPublish(t *testing.T) {
+	f := follow.New()
+	Publish("follow_test", &f)
+
+	var s follow.Stats
+	err := json.Unmarshal([]byte(expvar.Get("follow_test").String()), &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != (follow.Stats{}) {
+		t.Errorf("wrong stats: %#v", s)
+	}
+}
@@ -0,0 +1,259 @@
+// Package file writes followed lines to a file on disk.
+package file
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"zgo.at/follow"
+)
+
+// SyncPolicy controls when data is synced to disk with fsync.
+type SyncPolicy uint8
+
+const (
+	SyncRotate   SyncPolicy = iota // Sync when the file is rotated, and when Run returns.
+	SyncNever                      // Never sync; leave it to the OS.
+	SyncBytes                      // Sync after every SyncSize bytes, and on rotation.
+	SyncInterval                   // Sync every SyncInterval, and on rotation.
+)
+
+// File writes lines to a file, adding a newline after every line.
+//
+// Fields that are zero use their default. New sets all the defaults.
+type File struct {
+	// File to write to; it's created if it doesn't exist and appended to if
+	// it does.
+	Path string
+
+	// Permissions for new files.
+	//
+	// Default is 0o644.
+	Mode os.FileMode
+
+	// Size of the write buffer in bytes. Buffered lines are written once the
+	// buffer is full, on sync, or when there are no more lines waiting on the
+	// data channel, so they're never kept in memory while waiting for new
+	// lines.
+	//
+	// Default is 64K.
+	BufferSize int
+
+	// When to sync the file to disk; see SyncPolicy.
+	//
+	// Default is SyncRotate, which means only when the file is rotated and
+	// when Run returns.
+	Sync SyncPolicy
+
+	// Number of bytes for SyncBytes, and the interval for SyncInterval.
+	//
+	// Default is 1M and 1 second.
+	SyncSize     int64
+	SyncInterval time.Duration
+
+	// Rotate the file once it's this many bytes: it's renamed to Path.N, with
+	// the lowest N that doesn't exist yet, and a new file is started.
+	//
+	// Default is 0, which means the file is never rotated.
+	MaxSize int64
+}
+
+// New creates a new file sink with the default settings.
+func New(path string) *File {
+	return &File{
+		Path:         path,
+		Mode:         0o644,
+		BufferSize:   64 << 10,
+		SyncSize:     1 << 20,
+		SyncInterval: time.Second,
+	}
+}
+
+// Copy of f with the defaults for fields that are zero, so a File that wasn't
+// created with New works too.
+func (f *File) withDefaults() *File {
+	c := *f
+	if c.Mode == 0 {
+		c.Mode = 0o644
+	}
+	if c.BufferSize <= 0 {
+		c.BufferSize = 64 << 10
+	}
+	if c.SyncSize <= 0 {
+		c.SyncSize = 1 << 20
+	}
+	if c.SyncInterval <= 0 {
+		c.SyncInterval = time.Second
+	}
+	return &c
+}
+
+// Run writes all lines from data to the file, until io.EOF is received, the
+// channel is closed, or the context is cancelled.
+//
+// Data with an error other than io.EOF is skipped. The file is flushed and
+// closed (and synced, unless Sync is SyncNever) before this returns.
+//
+// This returns the first error from writing, syncing, or rotating, or
+// ctx.Err() if the context is cancelled.
+func (f *File) Run(ctx context.Context, data <-chan follow.Data) (err error) {
+	f = f.withDefaults()
+	o, err := f.open(f.Path)
+	if err != nil {
+		return fmt.Errorf("file.Run: %w", err)
+	}
+	defer func() {
+		if cerr := o.close(); err == nil && cerr != nil {
+			err = fmt.Errorf("file.Run: %w", cerr)
+		}
+	}()
+
+	var tick <-chan time.Time
+	if f.Sync == SyncInterval {
+		t := time.NewTicker(f.SyncInterval)
+		defer t.Stop()
+		tick = t.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick:
+			if err := o.sync(); err != nil {
+				return fmt.Errorf("file.Run: %w", err)
+			}
+		case d, ok := <-data:
+			if !ok || d.Err == io.EOF {
+				return nil
+			}
+			if d.Err != nil {
+				continue
+			}
+			if err := o.writeData(d); err != nil {
+				return fmt.Errorf("file.Run: %w", err)
+			}
+			if len(data) == 0 {
+				if err := o.w.Flush(); err != nil {
+					return fmt.Errorf("file.Run: %w", err)
+				}
+			}
+		}
+	}
+}
+
+// Used in tests.
+var fsync = (*os.File).Sync
+
+// An open output file.
+type output struct {
+	cfg      *File
+	path     string
+	fp       *os.File
+	w        *bufio.Writer
+	size     int64     // Size of the file, including what's buffered.
+	unsynced int64     // Bytes written since the last sync.
+	written  time.Time // Last write.
+}
+
+func (f *File) open(path string) (*output, error) {
+	fp, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, f.Mode)
+	if err != nil {
+		return nil, err
+	}
+	st, err := fp.Stat()
+	if err != nil {
+		fp.Close()
+		return nil, err
+	}
+	return &output{cfg: f, path: path, fp: fp, w: bufio.NewWriterSize(fp, f.BufferSize), size: st.Size()}, nil
+}
+
+func (o *output) writeData(d follow.Data) error {
+	if d.Batch != nil {
+		for _, l := range d.Batch {
+			if err := o.write(l); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return o.write(d.Bytes)
+}
+
+func (o *output) write(line []byte) error {
+	n := int64(len(line)) + 1
+	if o.cfg.MaxSize > 0 && o.size > 0 && o.size+n > o.cfg.MaxSize {
+		if err := o.rotate(); err != nil {
+			return err
+		}
+	}
+
+	o.w.Write(line)
+	if err := o.w.WriteByte('\n'); err != nil {
+		return err
+	}
+	o.size += n
+	o.unsynced += n
+	o.written = time.Now()
+	if o.cfg.Sync == SyncBytes && o.unsynced >= o.cfg.SyncSize {
+		return o.sync()
+	}
+	return nil
+}
+
+// Flush the buffer and sync the file to disk.
+func (o *output) sync() error {
+	err := o.w.Flush()
+	if err != nil {
+		return err
+	}
+	if o.unsynced == 0 {
+		return nil
+	}
+	o.unsynced = 0
+	return fsync(o.fp)
+}
+
+// Flush and close the file, syncing it unless Sync is SyncNever.
+func (o *output) close() error {
+	var err error
+	if o.cfg.Sync == SyncNever {
+		err = o.w.Flush()
+	} else {
+		err = o.sync()
+	}
+	if cerr := o.fp.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Close the file, rename it to path.N, and start a new file.
+func (o *output) rotate() error {
+	err := o.close()
+	if err != nil {
+		return err
+	}
+	for i := 1; ; i++ {
+		to := o.path + "." + strconv.Itoa(i)
+		if _, err := os.Lstat(to); os.IsNotExist(err) {
+			err = os.Rename(o.path, to)
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+	n, err := o.cfg.open(o.path)
+	if err != nil {
+		return err
+	}
+	*o = *n
+	return nil
+}
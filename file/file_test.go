@@ -0,0 +1,150 @@
+package file
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"zgo.at/follow"
+)
+
+// Count the calls to fsync.
+func countSync(t *testing.T) *int32 {
+	t.Helper()
+	n := new(int32)
+	fsync = func(fp *os.File) error {
+		atomic.AddInt32(n, 1)
+		return fp.Sync()
+	}
+	t.Cleanup(func() { fsync = (*os.File).Sync })
+	return n
+}
+
+func read(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func run(t *testing.T, f *File, lines ...string) {
+	t.Helper()
+	data := make(chan follow.Data, len(lines)+1)
+	for _, l := range lines {
+		data <- follow.Data{Bytes: []byte(l)}
+	}
+	data <- follow.Data{Err: io.EOF}
+	err := f.Run(context.Background(), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "out")
+	err := os.WriteFile(tmp, []byte("existing\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &File{Path: tmp}
+	data := make(chan follow.Data, 10)
+	data <- follow.Data{Bytes: []byte("one")}
+	data <- follow.Data{Err: io.ErrUnexpectedEOF}
+	data <- follow.Data{Batch: [][]byte{[]byte("two"), []byte("three")}}
+	close(data)
+	err = f.Run(context.Background(), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := read(t, tmp), "existing\none\ntwo\nthree\n"; got != want {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+// Lines are written once nothing is waiting on the channel, rather than when
+// the buffer is full.
+func TestRunFlush(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "out")
+	data := make(chan follow.Data)
+	done := make(chan error)
+	go func() { done <- New(tmp).Run(context.Background(), data) }()
+
+	data <- follow.Data{Bytes: []byte("one")}
+	time.Sleep(10 * time.Millisecond)
+	if got := read(t, tmp); got != "one\n" {
+		t.Errorf("got %q", got)
+	}
+	close(data)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunRotate(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "out")
+	n := countSync(t)
+	f := New(tmp)
+	f.MaxSize = 10
+	run(t, f, "one", "two", "three", "four", "five")
+
+	got := []string{read(t, tmp+".1"), read(t, tmp+".2"), read(t, tmp)}
+	want := []string{"one\ntwo\n", "three\n", "four\nfive\n"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+	if atomic.LoadInt32(n) != 3 {
+		t.Errorf("synced %d times; want 3", atomic.LoadInt32(n))
+	}
+}
+
+func TestSync(t *testing.T) {
+	tests := []struct {
+		sync SyncPolicy
+		want int32
+	}{
+		{SyncRotate, 1},
+		{SyncNever, 0},
+		{SyncBytes, 3}, // After "one two" and "three four", and on close.
+	}
+	for _, tt := range tests {
+		n := countSync(t)
+		f := New(filepath.Join(t.TempDir(), "out"))
+		f.Sync, f.SyncSize = tt.sync, 8
+		run(t, f, "one", "two", "three", "four", "five")
+		if atomic.LoadInt32(n) != tt.want {
+			t.Errorf("%d: synced %d times; want %d", tt.sync, atomic.LoadInt32(n), tt.want)
+		}
+	}
+
+	t.Run("interval", func(t *testing.T) {
+		n := countSync(t)
+		f := New(filepath.Join(t.TempDir(), "out"))
+		f.Sync, f.SyncInterval = SyncInterval, 10*time.Millisecond
+
+		data := make(chan follow.Data)
+		done := make(chan error)
+		go func() { done <- f.Run(context.Background(), data) }()
+		data <- follow.Data{Bytes: []byte("one")}
+		time.Sleep(50 * time.Millisecond)
+		data <- follow.Data{Bytes: []byte("two")}
+		close(data)
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+
+		// Once for "one" after the interval, and once for "two" on close;
+		// nothing is synced if nothing was written.
+		if atomic.LoadInt32(n) != 2 {
+			t.Errorf("synced %d times; want 2", atomic.LoadInt32(n))
+		}
+	})
+}
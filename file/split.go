@@ -0,0 +1,200 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"zgo.at/follow"
+)
+
+// Funcs are the functions available in path templates.
+//
+//	time   Timestamp for the line; see Timestamp.
+//	base   Last element of the path, like filepath.Base.
+var Funcs = template.FuncMap{
+	"time": Timestamp,
+	"base": filepath.Base,
+}
+
+// Timestamp gets the time for a line: Record.Timestamp if Follower.Parser set
+// it, or the time it was read otherwise. It's always in UTC.
+func Timestamp(d follow.Data) time.Time {
+	if !d.Timestamp.IsZero() {
+		return d.Timestamp.UTC()
+	}
+	return d.ReadAt.UTC()
+}
+
+// Split writes lines to files chosen by a template for the path, such as a file
+// for every service for every day, or a file for every input of a merged
+// stream.
+//
+// Fields that are zero use their default. NewSplit sets all the defaults.
+type Split struct {
+	// Template for the path; this gets a follow.Data, so labels from
+	// Record.Fields can be used with e.g.:
+	//
+	//	logs/{{index .Fields "service"}}/{{(time .).Format "2006-01-02"}}.log
+	//
+	// Or use {{base .Name}} to split by the file the line was read from. Use
+	// template.New("").Funcs(file.Funcs) to get the "time" and "base"
+	// functions. Directories are created as needed.
+	Path *template.Template
+
+	// Options for every file; File.Path is ignored, and MaxSize rotates every
+	// file on its own.
+	File File
+
+	// Close files that weren't written to for this long, such as the file
+	// for the previous day; it's opened again if there is a new line for it.
+	//
+	// Default is 5 minutes.
+	IdleClose time.Duration
+
+	// Keep at most this many files open; the file that was written to the
+	// longest ago is closed if another one needs to be opened.
+	//
+	// Default is 100.
+	MaxOpen int
+}
+
+// NewSplit creates a new split file sink with the default settings.
+func NewSplit(path *template.Template) *Split {
+	return &Split{
+		Path:      path,
+		File:      *New(""),
+		IdleClose: 5 * time.Minute,
+		MaxOpen:   100,
+	}
+}
+
+// Copy of s with the defaults for fields that are zero, so a Split that wasn't
+// created with NewSplit works too.
+func (s *Split) withDefaults() *Split {
+	c := *s
+	c.File = *c.File.withDefaults()
+	if c.IdleClose <= 0 {
+		c.IdleClose = 5 * time.Minute
+	}
+	if c.MaxOpen <= 0 {
+		c.MaxOpen = 100
+	}
+	return &c
+}
+
+// Run writes all lines from data to the files, until io.EOF is received, the
+// channel is closed, or the context is cancelled.
+//
+// Data with an error other than io.EOF is skipped. All files are flushed and
+// closed (and synced, unless Sync is SyncNever) before this returns.
+//
+// This returns the first error from the Path template, opening, writing,
+// syncing, or rotating a file, or ctx.Err() if the context is cancelled.
+func (s *Split) Run(ctx context.Context, data <-chan follow.Data) (err error) {
+	s = s.withDefaults()
+	outputs := make(map[string]*output)
+	defer func() {
+		for _, o := range outputs {
+			if cerr := o.close(); err == nil && cerr != nil {
+				err = fmt.Errorf("file.Split.Run: %w", cerr)
+			}
+		}
+	}()
+
+	tick := s.IdleClose
+	if s.File.Sync == SyncInterval && s.File.SyncInterval < tick {
+		tick = s.File.SyncInterval
+	}
+	t := time.NewTicker(tick)
+	defer t.Stop()
+
+	path := new(strings.Builder)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-t.C:
+			for p, o := range outputs {
+				if now.Sub(o.written) >= s.IdleClose {
+					delete(outputs, p)
+					err = o.close()
+				} else if s.File.Sync == SyncInterval {
+					err = o.sync()
+				}
+				if err != nil {
+					return fmt.Errorf("file.Split.Run: %w", err)
+				}
+			}
+		case d, ok := <-data:
+			if !ok || d.Err == io.EOF {
+				return nil
+			}
+			if d.Err != nil {
+				continue
+			}
+
+			path.Reset()
+			err := s.Path.Execute(path, d)
+			if err != nil {
+				return fmt.Errorf("file.Split.Run: %w", err)
+			}
+			o, err := s.output(outputs, path.String())
+			if err != nil {
+				return fmt.Errorf("file.Split.Run: %w", err)
+			}
+			if err := o.writeData(d); err != nil {
+				return fmt.Errorf("file.Split.Run: %w", err)
+			}
+
+			if len(data) == 0 {
+				for _, o := range outputs {
+					if err := o.w.Flush(); err != nil {
+						return fmt.Errorf("file.Split.Run: %w", err)
+					}
+				}
+			}
+		}
+	}
+}
+
+// Get the open file for the path, or open it, closing the file that was
+// written to the longest ago if there are more than MaxOpen.
+func (s *Split) output(outputs map[string]*output, path string) (*output, error) {
+	if o, ok := outputs[path]; ok {
+		return o, nil
+	}
+
+	if len(outputs) >= s.MaxOpen {
+		open := make([]string, 0, len(outputs))
+		for p := range outputs {
+			open = append(open, p)
+		}
+		sort.Slice(open, func(i, j int) bool { return outputs[open[i]].written.Before(outputs[open[j]].written) })
+		for _, p := range open[:len(outputs)-s.MaxOpen+1] {
+			err := outputs[p].close()
+			delete(outputs, p)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err := os.MkdirAll(filepath.Dir(path), 0o777)
+	if err != nil {
+		return nil, err
+	}
+	o, err := s.File.open(path)
+	if err != nil {
+		return nil, err
+	}
+	o.written = time.Now()
+	outputs[path] = o
+	return o, nil
+}
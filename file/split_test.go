@@ -0,0 +1,119 @@
+package file
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"text/template"
+	"time"
+
+	"zgo.at/follow"
+)
+
+// List all files in dir as "path: content".
+func list(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		files = append(files, strings.TrimPrefix(path, dir+string(filepath.Separator))+": "+read(t, path))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func TestSplit(t *testing.T) {
+	dir := t.TempDir()
+	s := NewSplit(template.Must(template.New("").Funcs(Funcs).Parse(
+		dir + `/{{index .Fields "app"}}/{{(time .).Format "2006-01-02"}}.log`)))
+	s.File.MaxSize = 10
+
+	line := func(app string, day int, l string) follow.Data {
+		return follow.Data{Bytes: []byte(l), Record: follow.Record{
+			Timestamp: time.Date(2024, 5, day, 14, 32, 0, 0, time.UTC),
+			Fields:    map[string]string{"app": app},
+		}}
+	}
+	data := make(chan follow.Data, 10)
+	for _, d := range []follow.Data{
+		line("a", 1, "one"), line("b", 1, "two"), line("a", 1, "three"),
+		line("a", 1, "four"), line("a", 2, "five"), {Err: io.EOF},
+	} {
+		data <- d
+	}
+	err := s.Run(context.Background(), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"a/2024-05-01.log.1: one\nthree\n",
+		"a/2024-05-01.log: four\n",
+		"a/2024-05-02.log: five\n",
+		"b/2024-05-01.log: two\n",
+	}
+	if got := list(t, dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+// Split a merged stream back by input file.
+func TestSplitName(t *testing.T) {
+	dir := t.TempDir()
+	s := &Split{Path: template.Must(template.New("").Funcs(Funcs).Parse(dir + `/{{base .Name}}`))}
+	s.MaxOpen = 1
+
+	data := make(chan follow.Data, 10)
+	for _, d := range []follow.Data{
+		{Name: "/var/log/x", Bytes: []byte("x1")}, {Name: "/var/log/y", Bytes: []byte("y1")},
+		{Name: "/var/log/x", Bytes: []byte("x2")}, {Err: io.EOF},
+	} {
+		data <- d
+	}
+	err := s.Run(context.Background(), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"x: x1\nx2\n", "y: y1\n"}
+	if got := list(t, dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestSplitIdleClose(t *testing.T) {
+	dir := t.TempDir()
+	n := countSync(t)
+	s := NewSplit(template.Must(template.New("").Parse(dir + `/{{.Name}}`)))
+	s.IdleClose = 10 * time.Millisecond
+
+	data := make(chan follow.Data)
+	done := make(chan error)
+	go func() { done <- s.Run(context.Background(), data) }()
+	data <- follow.Data{Name: "a", Bytes: []byte("one")}
+	time.Sleep(50 * time.Millisecond)
+	if atomic.LoadInt32(n) != 1 {
+		t.Errorf("not closed after IdleClose: synced %d times", atomic.LoadInt32(n))
+	}
+
+	data <- follow.Data{Name: "a", Bytes: []byte("two")}
+	close(data)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a: one\ntwo\n"}; !reflect.DeepEqual(list(t, dir), want) {
+		t.Errorf("\ngot:  %q\nwant: %q", list(t, dir), want)
+	}
+}
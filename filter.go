@@ -0,0 +1,66 @@
+package follow
+
+import (
+	"regexp"
+	"sync/atomic"
+)
+
+// Filter sends only lines that match, so the consumer isn't woken up for lines
+// it would discard anyway.
+//
+// For example, to send only lines containing ERROR or WARN, except health
+// checks:
+//
+//	f.Filter = &follow.Filter{
+//		Match:   regexp.MustCompile(`ERROR|WARN`),
+//		Exclude: []*regexp.Regexp{regexp.MustCompile(`GET /health`)},
+//	}
+//
+// Errors are always sent. Also see Severity, which is faster if you only need
+// to filter on the log level.
+type Filter struct {
+	// Lines must match this regexp; it's ignored if nil.
+	Match *regexp.Regexp
+
+	// Lines must match this condition; it's ignored if nil. This can be used
+	// to match on the structured data, e.g. Data.Fields from Logfmt.
+	Cond func(Data) bool
+
+	// Lines must not match any of these regexps.
+	Exclude []*regexp.Regexp
+
+	// Lines must not match this condition; it's ignored if nil.
+	ExcludeCond func(Data) bool
+
+	suppressed int64
+}
+
+// Suppressed returns the number of lines that were dropped by the filter.
+func (fl *Filter) Suppressed() int64 { return atomic.LoadInt64(&fl.suppressed) }
+
+// Report if this line should be sent.
+func (fl *Filter) keep(d Data) bool {
+	if fl.match(d) {
+		return true
+	}
+	atomic.AddInt64(&fl.suppressed, 1)
+	return false
+}
+
+func (fl *Filter) match(d Data) bool {
+	if fl.Match != nil && !fl.Match.Match(d.Bytes) {
+		return false
+	}
+	if fl.Cond != nil && !fl.Cond(d) {
+		return false
+	}
+	for _, re := range fl.Exclude {
+		if re.Match(d.Bytes) {
+			return false
+		}
+	}
+	if fl.ExcludeCond != nil && fl.ExcludeCond(d) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+package follow
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter *Filter
+		want   []string
+	}{
+		{"none", &Filter{}, []string{"ERROR one", "INFO two", "WARN three"}},
+		{"match", &Filter{Match: regexp.MustCompile(`ERROR|WARN`)}, []string{"ERROR one", "WARN three"}},
+		{"cond", &Filter{Cond: func(d Data) bool { return bytes.HasSuffix(d.Bytes, []byte("o")) }}, []string{"INFO two"}},
+		{"both", &Filter{
+			Match: regexp.MustCompile(`ERROR|WARN`),
+			Cond:  func(d Data) bool { return bytes.HasSuffix(d.Bytes, []byte("e")) },
+		}, []string{"ERROR one", "WARN three"}},
+		{"exclude", &Filter{Exclude: []*regexp.Regexp{regexp.MustCompile(`two`), regexp.MustCompile(`three`)}}, []string{"ERROR one"}},
+		{"exclude_cond", &Filter{ExcludeCond: func(d Data) bool { return bytes.HasPrefix(d.Bytes, []byte("ERROR")) }}, []string{"INFO two", "WARN three"}},
+		{"match_exclude", &Filter{
+			Match:   regexp.MustCompile(`ERROR|WARN`),
+			Exclude: []*regexp.Regexp{regexp.MustCompile(`one`)},
+		}, []string{"WARN three"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, tmp, lines := startWith(context.Background(), t, func(f *Follower) {
+				f.Filter = tt.filter
+			})
+			write(t, tmp, "ERROR one", "INFO two", "WARN three")
+			f.Stop()
+
+			if got := <-lines; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\ngot:  %q\nwant: %q", got, tt.want)
+			}
+			if s := tt.filter.Suppressed(); s != int64(3-len(tt.want)) {
+				t.Errorf("Suppressed() = %d; want %d", s, 3-len(tt.want))
+			}
+			if s := f.Stats().Filtered; s != int64(3-len(tt.want)) {
+				t.Errorf("Stats().Filtered = %d; want %d", s, 3-len(tt.want))
+			}
+		})
+	}
+}
@@ -4,97 +4,618 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/fsnotify/fsnotify"
 )
 
 type Data struct {
-	Err   error
+	Err  error
+	Name string // File name, as passed to Start.
+
+	// The line, without the newline. This shares memory with the other lines
+	// that were read at the same time, so copy it if you keep it around long
+	// after the rest are gone.
 	Bytes []byte
+
+	// Byte offset of the start of the line in the file. If Follower.Encoding
+	// is set this is the offset in the decoded text.
+	Offset int64
+
+	// Line number, starting at 1 for the first line that was read; lines that
+	// were in the file before Start aren't counted. This keeps counting after
+	// the file is rotated or truncated, unless Follower.ResetLines is set.
+	Line int64
+
+	// Time the line was read; this is the time the newline was read for lines
+	// that were written in several parts, and the time of the first line for
+	// Multiline records.
+	ReadAt time.Time
+
+	// Line was longer than Follower.MaxLineLen and was truncated or split.
+	// For a Batch it's set if any of the lines was.
+	Long bool
+
+	// Trace or request ID for this line if Follower.TraceID is set, to link
+	// the line to a distributed trace.
+	TraceID string
+
+	// Columns parsed from the record if Follower.CSV is set.
+	Columns []string
+
+	// Lines if Follower.Batch is set; Bytes is nil, and Offset, Line, and
+	// ReadAt are for the first line. The other per-line fields aren't set,
+	// except Long.
+	Batch [][]byte
+
+	// Structured data if Follower.Parser is set.
+	Record
+
+	chunk *chunk // Buffer Bytes is in, if Follower.Pool is set.
 }
 
+// Lines in this Data: Batch if it's set, or just Bytes.
+func (d Data) batch() [][]byte {
+	if d.Batch != nil {
+		return d.Batch
+	}
+	return [][]byte{d.Bytes}
+}
+
+// Overflow controls what to do with lines if the Data channel is full.
+type Overflow uint8
+
+const (
+	OverflowBlock      Overflow = iota // Wait until there is room; reading the file waits too.
+	OverflowDropNewest                 // Drop the line that doesn't fit.
+	OverflowDropOldest                 // Drop the oldest line in the channel to make room.
+)
+
+// ReopenAt controls where to start reading a file that was reopened after it
+// was removed or renamed.
+type ReopenAt uint8
+
+const (
+	ReopenStart ReopenAt = iota // Read from the start of the file.
+	ReopenEnd                   // Read from the end of the file, skipping everything before.
+)
+
+// LongLines controls what to do with lines longer than Follower.MaxLineLen.
+type LongLines uint8
+
+const (
+	LongTruncate LongLines = iota // Truncate to MaxLineLen, skipping the rest.
+	LongSplit                     // Split in chunks of MaxLineLen.
+)
+
+// InvalidUTF8 controls what to do with lines containing invalid UTF-8.
+type InvalidUTF8 uint8
+
+const (
+	UTF8Raw     InvalidUTF8 = iota // Send the bytes as-is.
+	UTF8Replace                    // Replace invalid bytes with U+FFFD.
+	UTF8Error                      // Send a Data with Err set to ErrInvalidUTF8, and the line in Bytes.
+)
+
 func (d Data) String() string { return string(d.Bytes) }
 
+type renderedError struct {
+	msg string
+	err error
+}
+
+func (e renderedError) Error() string { return e.msg }
+func (e renderedError) Unwrap() error { return e.err }
+
 type Follower struct {
-	Data   chan Data      // Data read from the file.
+	// Data read from the file.
+	//
+	// This is unbuffered, which means reading the file waits for every line to
+	// be received. Replace it with a buffered channel before Start to read
+	// ahead of a slow or bursty consumer:
+	//
+	//	f.Data = make(chan follow.Data, 1000)
+	Data chan Data
+
+	// What to do if the Data channel is full because the consumer is slower
+	// than the file is written to. Dropped lines are counted in Dropped, and an
+	// EventDrop is sent once lines fit again. Errors and the final io.EOF are
+	// never dropped, but OverflowDropOldest may drop an error that's waiting
+	// in the channel.
+	//
+	// OverflowDropOldest only makes sense if Data is buffered; it's the same
+	// as OverflowDropNewest for an unbuffered channel.
+	//
+	// Default is OverflowBlock, which means nothing is dropped.
+	Overflow Overflow
+
+	// Send lines in batches of up to this many lines in Data.Batch, rather
+	// than one Data per line. Lines read from the same write are batched
+	// together; it never waits for more lines to fill a batch. This is much
+	// faster for busy files, at the cost of per-line metadata such as
+	// Data.Record; use EventLong to find which line was long.
+	//
+	// Default is 0, which means lines aren't batched.
+	Batch int
+
+	// Read the file in chunks of this many bytes, sending the lines from every
+	// chunk before reading the next, so that appending a large amount of data
+	// at once doesn't read all of it in memory. Also see MaxLineLen.
+	//
+	// Default is 64K.
+	ReadSize int
+
+	// Reuse read buffers rather than allocating new ones for every read. Lines
+	// read at the same time share one buffer, which is reused once Release was
+	// called on all of them, so Data.Bytes must not be used after Release.
+	//
+	// This saves a lot of allocations for busy files, but it's easy to get
+	// wrong: only use it if you know you need it.
+	//
+	// Default is false, which means every read gets a new buffer and Data can
+	// be kept as long as you want.
+	Pool bool
+
 	Ready  chan struct{}  // Closed if everything is set up.
-	Reopen chan os.Signal // Send signal to reopen file.
+	Reopen chan os.Signal // Send signal to reopen file; see ReopenFile.
 
 	// Retry opening the file if it disappears for this period; this will
-	// attempt to open the file every second.
+	// attempt to open the file every second, or according to Backoff.
 	//
 	// Default is 2s; set to -1 to retry forever.
 	Retry time.Duration
 
-	file string
-	fp   *os.File
-	fpMu *sync.Mutex
-	stop chan error
+	// Before retrying according to Retry, Backoff, or RetryPolicy, try to
+	// reopen the file FastRetry times with FastRetryInterval in between. Most
+	// of the time a file that disappears is back right away, for example when
+	// an editor writes it, and there's no need to wait for the next retry.
+	//
+	// Default is 0 and 0, which means 10 times 25ms; set FastRetry to -1 to
+	// go straight to Retry, Backoff, or RetryPolicy.
+	FastRetry         int
+	FastRetryInterval time.Duration
+
+	// Wait longer between attempts to reopen the file; see Backoff.
+	//
+	// Default is nil, which means it tries every second.
+	Backoff *Backoff
+
+	// Decide when to try reopening the file, and when to give up, instead of
+	// Retry and Backoff; see RetryPolicy.
+	//
+	// Default is nil, which means Retry and Backoff are used.
+	RetryPolicy RetryPolicy
+
+	// Read the existing contents of the file from the start in Start, rather
+	// than only what's written after it, and send EventCaughtUp once reading
+	// reached the end. The file is read ReadSize bytes at a time, and anything
+	// written while it's being read is sent after it; nothing is missed.
+	//
+	// Default is false, which means only data written after Start is read,
+	// like tail -f.
+	FromStart bool
+
+	// Stop once the end of the file is reached, rather than waiting for more
+	// writes; set FromStart as well to read the entire file. A last line
+	// without a newline is sent as a line. The file isn't watched, so
+	// options such as Retry and Idle don't do anything.
+	//
+	// Default is false, which means it keeps following the file until Stop is
+	// called.
+	NoFollow bool
+
+	// How to watch the file for changes. WatchFile watches only the file
+	// rather than the directory it's in, if the system supports it.
+	//
+	// Default is WatchDir, which means the directory is watched with
+	// fsnotify.
+	Watch Watch
+
+	// Where to start reading a file that's reopened after it was removed or
+	// renamed, such as after a log rotation. The number of skipped bytes is
+	// set in Event.Skipped for EventReappear. Also see MaxAge.
+	//
+	// Default is ReopenStart, which means everything that was written to the
+	// new file is read.
+	ReopenAt ReopenAt
+
+	// Read what's left in a file that was removed, renamed, or replaced before
+	// opening the new one, and send a last line without a newline as a line.
+	//
+	// Default is false, which means only what was read before the file went
+	// away is sent, and a partial last line is dropped.
+	ReadRotated bool
+
+	// Skip data older than this: lines with a Record.Timestamp before this are
+	// dropped, and a file that was last modified before this is read from the
+	// end rather than the start when it's reopened, or when it's first opened
+	// with FromStart or Since.
+	//
+	// Default is 0, which means nothing is skipped.
+	MaxAge time.Duration
+
+	// Start reading at the first line logged at or after a time; see Since.
+	//
+	// Default is nil, which means it starts at the end, or the start if
+	// FromStart is set.
+	Since *Since
+
+	// Maximum line length in bytes; lines longer than this are truncated or
+	// split according to LongLines, and have Data.Long set. This prevents a
+	// runaway writer from using unbounded memory while we wait for a newline.
+	//
+	// Default is 0, which means no limit.
+	MaxLineLen int
+
+	// What to do with lines longer than MaxLineLen; default is LongTruncate.
+	// EventLong is sent for every line that's longer.
+	LongLines LongLines
+
+	// Add this to the end of lines that were truncated with LongTruncate,
+	// such as " [truncated]"; the line with the marker is still MaxLineLen.
+	//
+	// Default is "", which means nothing is added.
+	LongMarker string
+
+	// Reset Data.Line after the file is rotated or truncated, so it's the
+	// line number in the current file.
+	ResetLines bool
+
+	// Decode the file from this encoding to UTF-8 before splitting it in
+	// lines. See the Decoder documentation for details.
+	//
+	// Default is nil, which means bytes are read as-is.
+	Encoding Decoder
+
+	// Record all filesystem events and reads to this writer, which can be
+	// played back with Replay to debug problems. See Replay for the format.
+	//
+	// Default is nil, which means nothing is recorded.
+	Trace io.Writer
+
+	// Log diagnostic messages; see Logger.
+	//
+	// Default is nil, which means nothing is logged.
+	Log Logger
+
+	// What to do with lines containing invalid UTF-8; default is UTF8Raw.
+	InvalidUTF8 InvalidUTF8
+
+	// Remove ANSI escape sequences, such as colours, from lines before they're
+	// parsed or sent. Data.Offset is still the offset in the file. Lines longer
+	// than MaxLineLen aren't stripped.
+	//
+	// Default is false, which means lines are sent as-is.
+	StripANSI bool
+
+	// Read CSV records with this field delimiter (e.g. ',' or '\t') and set
+	// Data.Columns. Quoted fields can contain newlines, in which case a
+	// single Data contains several lines.
+	//
+	// Default is 0, which means lines aren't parsed as CSV.
+	CSV rune
+
+	// Parse lines with this parser and set Data.Record; see Parser.
+	//
+	// Default is nil, which means lines aren't parsed.
+	Parser Parser
+
+	// Join several lines in to one record; the lines are joined before being
+	// sent to Parser. See Multiline.
+	//
+	// Default is nil, which means every line is sent on its own.
+	Multiline *Multiline
+
+	// Wait before reopening the file if it's rotated too often; see Storm.
+	//
+	// Default is nil, which means the file is always reopened immediately.
+	Storm *Storm
+
+	// Send only lines that match; see Filter.
+	//
+	// Default is nil, which means every line is sent.
+	Filter *Filter
+
+	// Send only some lines; see Sample.
+	//
+	// Default is nil, which means every line is sent.
+	Sample *Sample
+
+	// Change or drop lines, in order, after Filter and Sample and before Dedup
+	// and Alerts; see Transform.
+	Transforms []Transform
+
+	// Limit how fast lines are sent; see Rate.
+	//
+	// Default is nil, which means there is no limit.
+	Rate *Rate
+
+	// Get a trace ID from every line to set Data.TraceID; this is called
+	// after Parser, so it can use the Record, and before Transforms, so it
+	// still sees an ID that gets redacted. See TraceIDField and
+	// TraceIDRegexp.
+	//
+	// Default is nil, which means Data.TraceID is never set.
+	TraceID func(Data) string
+
+	// Drop lines that are duplicates of recently sent lines; see Dedup.
+	//
+	// Default is nil, which means duplicates are sent.
+	Dedup *Dedup
+
+	// Call functions for lines that match alert rules; see Alert.
+	Alerts []*Alert
+
+	// Skip lines that don't have a severity token; this is applied before any
+	// other processing such as CSV and Parser. See Severity.
+	//
+	// Default is nil, which means no lines are skipped.
+	Severity *Severity
+
+	// Render error messages before they're sent on the Data channel, for
+	// example to translate them. The original error is still available with
+	// errors.Is and errors.As.
+	//
+	// Default is nil, which means errors are sent as-is.
+	RenderError func(error) string
+
+	// Send errors on this channel instead of the Data channel, so that Data
+	// only has lines. Lines that have an error (such as invalid UTF-8 with
+	// UTF8Error) are still sent on Data with Err set, in addition to sending
+	// the error here. io.EOF is always sent on Data.
+	//
+	// Default is nil, which means errors are sent on Data.
+	Errors chan error
+
+	// Send lifecycle events such as rotation and truncation on this channel;
+	// see Event. Events aren't dropped, so this must be read if it's set.
+	//
+	// Default is nil, which means no events are sent.
+	Events chan Event
+
+	// Call these functions for lifecycle events and errors, as an alternative
+	// to reading the Events channel or checking Data.Err.
+	//
+	// OnOpen is called when the file is opened or reopened, OnRotate when it's
+	// renamed or removed, OnTruncate when it's truncated, and OnIdle for
+	// EventIdle. OnError is called for every error sent on Data, except io.EOF.
+	//
+	// The functions are called from the goroutine reading the file, so they
+	// should be fast and shouldn't call Stop.
+	OnOpen     func(Event)
+	OnRotate   func(Event)
+	OnTruncate func(Event)
+	OnIdle     func(Event)
+	OnError    func(error)
+
+	// Send EventIdle if no data was read for this period, and again every
+	// period after that until new data is read. This can be used to tell "the
+	// program stopped logging" apart from "the follower is stuck".
+	//
+	// Default is 0, which means EventIdle is never sent.
+	Idle time.Duration
+
+	// Stop if no data was read and there were no lifecycle events for this
+	// period, for example to follow a log only while a batch job is writing
+	// to it. Start returns nil, as it does after Stop.
+	//
+	// Default is 0, which means it never stops on its own.
+	IdleStop time.Duration
+
+	// Stop after a line matching this regexp, for example to follow a log
+	// until a program is ready. The matching line is sent (unless Filter or
+	// another option drops it), but nothing after it. Start returns nil, as it
+	// does after Stop.
+	//
+	// Default is nil, which means it never stops on a line.
+	StopOn *regexp.Regexp
+
+	// Stop after sending this many lines, or this many bytes (not counting
+	// newlines). A line that would go over MaxBytes isn't sent. Lines dropped
+	// by Filter, Sample, etc. don't count. Stats.Skipped records how much of
+	// the file was left when it stopped.
+	//
+	// Default is 0, which means there is no limit.
+	MaxLines int
+	MaxBytes int64
+
+	name     string // As passed to Start.
+	file     string // Absolute path.
+	fp       *os.File
+	fpMu     *sync.Mutex
+	stop     *sync.Once
+	done     chan struct{} // Closed by Stop.
+	exit     *exit
+	dropped  *int64 // Number of lines dropped by Overflow.
+	stats    *stats
+	dropping int64  // Lines dropped since the last EventDrop.
+	long     bool   // In the middle of a line longer than MaxLineLen.
+	longAt   int64  // Offset of the last line longer than MaxLineLen, for EventLong.
+	partial  []byte // Partial line without newline from the last read.
+	chunk    []byte // Scratch buffer for reading.
+
+	undecoded []byte // Incomplete encoded sequence from the last read.
+	record    *Data  // Record that continues on the next line.
+	offset    int64  // Offset of the start of partial.
+	lineno    int64  // Number of the last complete line.
+	skipped   int64  // Bytes skipped by ReopenEnd.
+	readAt    time.Time
+
+	multi         *Data           // Current multiline record.
+	multiTimer    *time.Timer     // Send multi after Multiline.Timeout.
+	idleTimer     *time.Timer     // Send EventIdle after Idle.
+	idleStopTimer *time.Timer     // Stop after IdleStop.
+	stopAfter     bool            // Stop after the current line: StopOn matched, or MaxLines or MaxBytes was reached.
+	budgetLines   int             // Lines counted for MaxLines.
+	budgetBytes   int64           // Bytes counted for MaxBytes.
+	budgetHit     bool            // MaxLines or MaxBytes was reached.
+	skipFrom      int64           // Offset of the first line that wasn't sent because of budgetHit, or -1 if that's the next line.
+	attempt       int             // Number of reopen attempts since the file went missing.
+	attemptErr    error           // Error from the last reopen attempt.
+	goneAt        time.Time       // Time the file went missing.
+	reopenReq     chan chan error // Sent by ReopenFile.
+	renamedTo     string          // Where the file was renamed to, for EventRotate.
+
+	events []Event // Events not yet sent on Events.
+
+	// State for options, which is kept here so that the same option can be
+	// used for several followers.
+	sinceFound bool                   // The first line at or after Since.Time plus Skew was read.
+	sinceKeep  bool                   // The last line with a timestamp was sent by Since.
+	dedup      dedupState             // Lines seen by Dedup.
+	alerts     map[*Alert]*alertState // State for every Alert.
+	rate       rateState              // Tokens for Rate.
+	sampled    int                    // Lines seen by Sample, modulo Sample.Every.
+	rotations  []time.Time            // Recent rotations, for Storm.
+	severity   [][]byte               // Severity.Tokens.
+
+	state           state
+	retryInterval   time.Duration // Time between reopen attempts.
+	waitingInterval time.Duration // Time between EventWaiting events.
 }
 
 func New() Follower {
 	return Follower{
-		Ready:  make(chan struct{}),
-		Data:   make(chan Data),
-		Reopen: make(chan os.Signal, 1),
-		Retry:  2 * time.Second,
-		stop:   make(chan error),
-		fpMu:   new(sync.Mutex),
+		Ready:     make(chan struct{}),
+		Data:      make(chan Data),
+		Reopen:    make(chan os.Signal, 1),
+		reopenReq: make(chan chan error),
+		Retry:     2 * time.Second,
+		stop:      new(sync.Once),
+		done:      make(chan struct{}),
+		exit:      &exit{done: make(chan struct{})},
+		dropped:   new(int64),
+		stats:     new(stats),
+
+		retryInterval:   1 * time.Second,
+		waitingInterval: 10 * time.Second,
+		fpMu:            new(sync.Mutex),
 	}
 }
 
 // Stop following a file for changes.
-func (f Follower) Stop() {
-	f.stop <- nil
-	f.fpMu.Lock()
-	f.fp = nil
-	f.fpMu.Unlock()
+//
+// This returns immediately; Start will send io.EOF on the Data channel and
+// return. It's safe to call this more than once, from any goroutine, and
+// before Start or after Start returned.
+func (f *Follower) Stop() {
+	f.stop.Do(func() { close(f.done) })
 }
 
 // Start following a file for changes.
+//
+// This blocks until Stop is called or the context is cancelled; see Go to
+// start in the background. After that nothing else is sent on any channel,
+// except for a final Data with io.EOF, and Start returns once that was read.
 func (f *Follower) Start(ctx context.Context, file string) error {
-	var err error
-	f.file, err = filepath.Abs(file)
+	err := f.start(ctx, file)
+	f.exit.err = err
+	close(f.exit.done)
+	return err
+}
+
+func (f *Follower) start(ctx context.Context, file string) error {
+	abs, err := filepath.Abs(file)
 	if err != nil {
 		return err
 	}
 
+	f.fpMu.Lock()
+	f.name, f.file = file, abs
 	err = f.openFile(false)
-	if err != nil {
-		return err
+	if err == nil {
+		f.state = stateFollowing
+		f.traceOpen(EventOpen)
 	}
-	defer f.fp.Close()
-
-	w, err := fsnotify.NewWatcher()
+	f.fpMu.Unlock()
 	if err != nil {
 		return err
 	}
+	defer func() {
+		f.fpMu.Lock()
+		defer f.fpMu.Unlock()
+		f.fp.Close()
+		f.state = stateStopped
+	}()
 
-	// Watch the directory rather than the file; there doesn't seem to be any
-	// event sent when removing a file (on my Linux system, anyway).
-	// TODO: add support for multiple files; we need to be a bit smart about now
-	// watching the same dir twice.
-	err = w.Add(filepath.Dir(f.file))
-	if err != nil {
-		return err
+	var w watcher
+	if !f.NoFollow {
+		w, err = f.newWatcher()
+		if err != nil {
+			return watchError(filepath.Dir(f.file), err)
+		}
+		defer w.Close()
+
+		err = w.opened(f.fp)
+		if err != nil {
+			return watchError(filepath.Dir(f.file), err)
+		}
 	}
 
 	// Keep reading until we get a stop signal from mainloop.
+	f.stopOnCancel(ctx)
+	f.info(EventOpen.String(), "offset", f.offset)
+	f.resetIdleTimer()
+	loop := make(chan struct{})
 	go func() {
+		defer close(loop)
+		f.sendEvent(f.newEvent(EventOpen))
+		if f.fromStart() || f.NoFollow {
+			f.readSend()
+			if f.fromStart() && !f.stopped() {
+				f.event(EventCaughtUp)
+				f.sendEvents()
+			}
+		}
+		if f.NoFollow {
+			f.fpMu.Lock()
+			lines := f.flushPartial()
+			f.fpMu.Unlock()
+
+			f.sendAll(lines)
+			f.info("stopping at the end of the file because NoFollow is set", "offset", f.offset)
+			f.Stop()
+			return
+		}
 		for f.mainloop(ctx, w) {
+			f.sendEvents()
 		}
 	}()
 
 	close(f.Ready)
-	s := <-f.stop
-	f.Data <- Data{Err: io.EOF}
-	return s
+	<-f.done
+
+	// Wait for the loop to exit, so that nothing is sent after the io.EOF and
+	// nothing is reading when the file is closed.
+	<-loop
+	if err := ctx.Err(); err != nil && err != context.Canceled {
+		f.sendFinal(Data{Err: err})
+	}
+	f.sendFinal(Data{Err: io.EOF})
+	return nil
+}
+
+// Call Stop when the context is cancelled, so that nothing keeps waiting on a
+// consumer that went away.
+func (f *Follower) stopOnCancel(ctx context.Context) {
+	if ctx.Done() == nil {
+		return
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			f.Stop()
+		case <-f.done:
+		}
+	}()
 }
 
 // Note: callers should lock!
@@ -103,23 +624,63 @@ func (f *Follower) openFile(reopen bool) error {
 	if err != nil {
 		return err
 	}
+	if st, err := fp.Stat(); err == nil && st.IsDir() {
+		fp.Close()
+		return fmt.Errorf("%w: %q", ErrIsDir, f.file)
+	}
 
+	f.reset()
 	if f.fp != nil {
 		*f.fp = *fp
 	} else {
 		f.fp = fp
 	}
 
-	if !reopen {
-		_, err := f.fp.Seek(0, io.SeekEnd)
+	f.skipped = 0
+	first := f.state == stateNew && f.fromStart()
+	if first && f.tooOld(fp) {
+		first = false
+		f.debug("reading from the end because the file is older than MaxAge")
+	}
+	if first && f.Since != nil {
+		f.offset, err = f.seekSince(f.fp)
+		if err == nil {
+			_, err = f.fp.Seek(f.offset, io.SeekStart)
+		}
 		if err != nil {
 			return err
 		}
 	}
+	if (!reopen && !first) || (reopen && (f.ReopenAt == ReopenEnd || f.tooOld(fp))) {
+		f.offset, err = f.fp.Seek(0, io.SeekEnd)
+		if err != nil {
+			return err
+		}
+		if reopen {
+			f.skipped = f.offset
+			if f.ReopenAt == ReopenEnd {
+				f.debug("reading from the end because ReopenAt is ReopenEnd", "skipped", f.skipped)
+			} else {
+				f.debug("reading from the end because the file is older than MaxAge", "skipped", f.skipped)
+			}
+		}
+	}
 
 	return nil
 }
 
+// Report if the existing file should be read from the start.
+func (f *Follower) fromStart() bool { return f.FromStart || f.Since != nil }
+
+// Report if the file was last modified before MaxAge.
+func (f *Follower) tooOld(fp *os.File) bool {
+	if f.MaxAge <= 0 {
+		return false
+	}
+	st, err := fp.Stat()
+	return err == nil && time.Since(st.ModTime()) > f.MaxAge
+}
+
 func (f *Follower) reopen() error {
 	f.fpMu.Lock()
 	defer f.fpMu.Unlock()
@@ -129,126 +690,950 @@ func (f *Follower) reopen() error {
 	if err != nil {
 		return err
 	}
+	f.traceOpen(EventReopen)
+	f.event(EventReopen)
 	return nil
 }
 
-func (f *Follower) mainloop(ctx context.Context, w *fsnotify.Watcher) bool {
+// ReopenFile closes and reopens the file, for example after logrotate's
+// copytruncate or a SIGHUP to the writer. This is the same as sending on
+// Reopen, except that it waits for the reopen and returns the error, rather
+// than sending it on Data.
+//
+// This blocks until Start is following the file; if the file is gone and Start
+// is waiting for it to reappear it tries to open it right away, and returns the
+// error if that fails. It returns an error if the Follower is stopped.
+//
+// On Windows the file may be locked by the writer or pending delete; it's
+// retried like a file that went away if Retry is set, and this returns
+// ErrCannotReopen if that gives up.
+func (f *Follower) ReopenFile() error {
+	errc := make(chan error, 1)
 	select {
-	case <-ctx.Done():
-		err := ctx.Err()
-		if err != nil && err != context.Canceled {
-			f.Data <- Data{Err: err}
-		}
-		f.stop <- nil
+	case <-f.done:
+		return errors.New("follow.ReopenFile: stopped")
+	case f.reopenReq <- errc:
+	}
+	select {
+	case <-f.done:
+		return errors.New("follow.ReopenFile: stopped")
+	case err := <-errc:
+		return err
+	}
+}
+
+func (f *Follower) mainloop(ctx context.Context, w watcher) bool {
+	select {
+	case <-f.done:
 		return false
 
-	case err, ok := <-w.Errors:
+	case err, ok := <-w.Errors():
 		if !ok {
 			return true
 		}
-		f.Data <- Data{Err: err}
+		f.trace("error", nil, err)
+		if !errors.Is(err, fsnotify.ErrEventOverflow) {
+			f.send(Data{Err: watchError(filepath.Dir(f.file), err)})
+			return true
+		}
+
+		// We may have missed writes, so read the file to catch up.
+		f.info("watcher overflowed; reading the file to catch up")
+		f.send(Data{Err: ErrWatcherOverflow})
+		f.readSend()
 
 	case <-f.Reopen:
 		err := f.reopen()
+		if busy(err) && f.retries() {
+			return f.waitBusy(ctx, w, err, nil)
+		}
 		if err != nil {
-			f.Data <- Data{Err: err}
+			f.send(Data{Err: err})
+		} else {
+			f.watchOpened(w)
+		}
+
+	case errc := <-f.reopenReq:
+		err := f.reopen()
+		if busy(err) && f.retries() {
+			return f.waitBusy(ctx, w, err, errc)
+		}
+		errc <- err
+		if err == nil {
+			f.watchOpened(w)
+		}
+
+	case <-f.idleTimeout():
+		f.event(EventIdle)
+		resetTimer(&f.idleTimer, f.Idle)
+
+	case <-f.idleStopTimeout():
+		f.info("stopping because nothing happened for IdleStop", "idle_stop", f.IdleStop)
+		f.Stop()
+		return false
+
+	case <-f.multiTimeout():
+		f.sendMultiline()
+
+	case e, ok := <-w.Events():
+		if !ok {
+			return true
+		}
+
+		// The directory itself was removed or renamed, which also removes the
+		// watch.
+		if e.Name == filepath.Dir(f.file) && e.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+			f.trace(e.Op.String(), nil, nil)
+			return f.dirGone(ctx, w, e.Op)
 		}
 
-	case e, ok := <-w.Events:
 		// Since we read the directory this event may be for another file.
-		if !ok || e.Name != f.file {
+		if e.Name != f.file {
 			return true
 		}
 
+		f.trace(e.Op.String(), nil, nil)
+
 		// Write event; read as much data as we can, split it in lines, and send
 		// it over the channel.
 		if e.Op&fsnotify.Write == fsnotify.Write {
-			f.fpMu.Lock()
-			d, err := ioutil.ReadAll(f.fp)
+			f.readSend()
+		}
+
+		// File got deleted or moved; attempt to reopen.
+		if e.Op&fsnotify.Remove == fsnotify.Remove || e.Op&fsnotify.Rename == fsnotify.Rename {
+			return f.gone(ctx, w, e.Op, false)
+		}
+
+		// Another file was renamed over it, for example by a deploy tool that
+		// writes a new file and moves it in place; there's no event for the
+		// old file in this case.
+		if e.Op&fsnotify.Create == fsnotify.Create && f.replaced() {
+			return f.gone(ctx, w, fsnotify.Rename, false)
+		}
+	}
+	return true
+}
+
+// The directory was removed or renamed.
+//
+// If the directory was removed with everything in it we'll get an event for
+// the file first, and it may have been reopened already by the time we get
+// the event for the directory; we just need to watch it again in that case.
+func (f *Follower) dirGone(ctx context.Context, w watcher, op fsnotify.Op) bool {
+	if st, err := os.Stat(f.file); err == nil {
+		if cur, err := f.fp.Stat(); err == nil && os.SameFile(st, cur) {
+			f.rewatch(w)
+			return true
+		}
+	}
+	f.info("directory went away", "dir", filepath.Dir(f.file))
+	return f.gone(ctx, w, op, true)
+}
+
+// The file was removed or renamed; wait for it to come back, and watch the
+// directory again if rewatch is set. Returns false if we should stop.
+func (f *Follower) gone(ctx context.Context, w watcher, op fsnotify.Op, rewatch bool) bool {
+	if f.ReadRotated {
+		f.readSend()
+		f.fpMu.Lock()
+		lines := f.flushPartial()
+		f.fpMu.Unlock()
+
+		f.sendAll(lines)
+	}
+	if op&fsnotify.Rename == fsnotify.Rename {
+		f.renamedTo = w.renamedTo()
+		f.event(EventRotate)
+	} else {
+		f.event(EventRemove)
+	}
+	if !f.retries() {
+		f.sendEvents()
+		f.send(Data{Err: ErrFileGone})
+		f.Stop()
+		return false
+	}
+
+	f.fpMu.Lock()
+	f.fp.Close()
+	f.state = stateWaiting
+	f.fpMu.Unlock()
+	ok := f.stormWait(ctx) && f.retry(ctx)
+	switch {
+	case ok:
+		f.traceOpen(EventReappear)
+		f.event(EventReappear)
+		f.watchOpened(w)
+		if rewatch {
+			f.sendEvents()
+			f.rewatch(w)
+		}
+		return true
+	case ctx.Err() != nil:
+		return true // Stop on the next loop.
+	case f.stopped():
+		return false
+	}
+
+	f.giveUp()
+	return false
+}
+
+// The file couldn't be reopened after Reopen because another process has it
+// locked, or because it's being deleted but still open (both happen on
+// Windows); wait for it like it went away, rather than sending the error.
+//
+// The result is sent on errc if it's not nil.
+func (f *Follower) waitBusy(ctx context.Context, w watcher, err error, errc chan<- error) bool {
+	f.info("file is busy; waiting to reopen it", "err", err)
+	f.fpMu.Lock()
+	f.state = stateWaiting
+	f.fpMu.Unlock()
+
+	ok := f.retry(ctx)
+	if errc != nil {
+		if ok {
+			errc <- nil
+		} else {
+			errc <- ErrCannotReopen
+		}
+	}
+	switch {
+	case ok:
+		f.traceOpen(EventReopen)
+		f.event(EventReopen)
+		f.watchOpened(w)
+		return true
+	case ctx.Err() != nil:
+		return true // Stop on the next loop.
+	case f.stopped():
+		return false
+	}
+	f.giveUp()
+	return false
+}
+
+// Retry or RetryPolicy is set.
+func (f *Follower) retries() bool { return f.Retry != 0 || f.RetryPolicy != nil }
+
+func (f *Follower) giveUp() {
+	f.info("giving up on reopening the file", "retry", f.Retry)
+	f.sendEvents()
+	f.send(Data{Err: ErrCannotReopen})
+	f.Stop()
+}
+
+// Report if the file name now refers to a different file than the one we're
+// reading.
+func (f *Follower) replaced() bool {
+	st, err := os.Stat(f.file)
+	if err != nil {
+		return false
+	}
+	cur, err := f.fp.Stat()
+	return err == nil && !os.SameFile(st, cur)
+}
+
+// Watch the directory again after it was removed or renamed, and read what was
+// written before the watch was set up.
+func (f *Follower) rewatch(w watcher) {
+	dir := filepath.Dir(f.file)
+	err := w.rewatch(dir)
+	if err != nil {
+		f.send(Data{Err: watchError(dir, err)})
+		return
+	}
+	f.debug("watching directory again", "dir", dir)
+	f.readSend()
+}
+
+// Watch the file again after it was reopened, if the watcher needs that.
+func (f *Follower) watchOpened(w watcher) {
+	f.fpMu.Lock()
+	err := w.opened(f.fp)
+	f.fpMu.Unlock()
+	if err != nil {
+		f.send(Data{Err: watchError(filepath.Dir(f.file), err)})
+	}
+}
+
+// Try to reopen the file after it went away. This returns false if we couldn't
+// reopen it within f.Retry, or if the context was cancelled or Stop called.
+func (f *Follower) retry(ctx context.Context) bool {
+	var isDir bool
+	f.attempt, f.attemptErr, f.goneAt = 0, nil, time.Now()
+	try := func() bool {
+		f.fpMu.Lock()
+		err := f.openFile(true)
+		if err == nil {
+			f.state = stateFollowing
+		}
+		f.fpMu.Unlock()
+		f.attempt++
+		f.attemptErr = err
+		if err != nil {
+			f.debug("reopen failed", "attempt", f.attempt, "err", err)
+			// Send this once, as it's probably not what anyone expected.
+			if errors.Is(err, ErrIsDir) && !isDir {
+				f.send(Data{Err: err})
+			}
+			isDir = errors.Is(err, ErrIsDir)
+		}
+		return err == nil
+	}
+
+	n, wait := f.FastRetry, f.FastRetryInterval
+	if n == 0 {
+		n = 10
+	}
+	if wait <= 0 {
+		wait = 25 * time.Millisecond
+	}
+	for i := 0; i < n; i++ {
+		if try() {
+			return true
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return false
+		case <-f.done:
+			t.Stop()
+			return false
+		case <-t.C:
+		}
+	}
+
+	var (
+		start   = time.Now()
+		waiting = start
+		policy  = f.RetryPolicy
+		t       *time.Timer
+	)
+	if policy == nil {
+		policy = f.retryPolicy
+	}
+	defer func() {
+		if t != nil {
+			t.Stop()
+		}
+	}()
+	for n := 0; ; n++ {
+		wait, giveUp := policy(n, time.Since(start))
+		if giveUp {
+			return false
+		}
+		resetTimer(&t, wait)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-f.done:
+			return false
+		case <-f.idleStopTimeout():
+			f.info("stopping because nothing happened for IdleStop", "idle_stop", f.IdleStop)
+			f.Stop()
+			return false
+		case errc := <-f.reopenReq:
+			ok := try()
+			errc <- f.attemptErr
+			if ok {
+				return true
+			}
+			continue
+		case <-t.C:
+		}
+
+		if try() {
+			return true
+		}
+		if time.Since(waiting) >= f.waitingInterval {
+			waiting = time.Now()
+			f.event(EventWaiting)
+			f.sendEvents()
+		}
+	}
+}
+
+func (f *Follower) stopped() bool { return isClosed(f.done) }
+
+// Report if c is closed; a nil channel is never closed.
+func isClosed(c <-chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+// Read and send everything that was written since the last read, one chunk of
+// ReadSize at a time.
+func (f *Follower) readSend() {
+	for {
+		f.fpMu.Lock()
+		lines, more := f.read()
+		f.fpMu.Unlock()
+
+		f.sendAll(lines)
+		if !more || f.stopped() {
+			return
+		}
+	}
+}
+
+// Read up to ReadSize bytes of new data from the file and split it in lines;
+// more is set if there may be more data to read.
+//
+// Note: callers should lock!
+func (f *Follower) read() (data []Data, more bool) {
+	f.readAt = time.Now()
+	d, c, more, err := f.readChunk()
+	if err != nil {
+		data = append(data, Data{Err: err})
+	}
+
+	// We didn't read any data, the file may have been truncated. This is not
+	// easy to detect since it appears as just a "WRITE" event.
+	if len(d) == 0 {
+		cur, _ := f.fp.Seek(0, io.SeekCurrent)
+		end, _ := f.fp.Seek(0, io.SeekEnd)
+
+		// Seek cursor is past the end of the file, which means it got smaller
+		// and (probably) truncated. Seek to the start and read again.
+		if cur > end {
+			f.debug("read position is past the end of the file; assuming it was truncated", "position", cur, "size", end)
+			f.trace("truncate", nil, nil)
+			f.reset()
+			f.fp.Seek(0, io.SeekStart)
+			f.event(EventTruncate)
+			c.hold(nil)
+			d, c, more, err = f.readChunk()
 			if err != nil {
-				f.Data <- Data{Err: err}
-			}
-
-			// We didn't read any data, the file may have been truncated. This
-			// is not easy to detect since it appears as just a "WRITE" event.
-			if len(d) == 0 {
-				cur, _ := f.fp.Seek(0, io.SeekCurrent)
-				end, _ := f.fp.Seek(0, io.SeekEnd)
-
-				// Seek cursor is past the end of the file, which means it got
-				// smaller and (probably) truncated. Seek to the start and read
-				// again.
-				if cur > end {
-					f.fp.Seek(0, io.SeekStart)
-					d, err = ioutil.ReadAll(f.fp)
-					if err != nil {
-						f.Data <- Data{Err: err}
-					}
-				} else {
-					f.fp.Seek(cur, io.SeekStart)
-				}
+				data = append(data, Data{Err: err})
 			}
+		} else {
+			f.fp.Seek(cur, io.SeekStart)
+		}
+	}
+
+	f.trace("read", d, err)
+	if len(d) > 0 {
+		f.resetIdleTimer()
+	}
+	lines := f.process(d)
+	c.hold(lines)
+	if len(data) == 0 {
+		return lines, more
+	}
+	return append(data, lines...), more
+}
+
+// Note: callers should lock!
+func (f *Follower) readChunk() ([]byte, *chunk, bool, error) {
+	size := f.ReadSize
+	if size <= 0 {
+		size = 64 << 10
+	}
+
+	var c *chunk
+	if f.Pool {
+		c = getChunk(size)
+	} else if len(f.chunk) != size {
+		f.chunk = make([]byte, size)
+	}
+
+	var (
+		n   int
+		err error
+		d   []byte
+	)
+	if c != nil {
+		n, err = io.ReadFull(f.fp, c.buf)
+		d = c.buf[:n]
+	} else {
+		// Copy from the scratch buffer, so that lines from a small write don't
+		// keep a large buffer alive.
+		n, err = io.ReadFull(f.fp, f.chunk)
+		d = append([]byte(nil), f.chunk[:n]...)
+	}
+	switch err {
+	case nil:
+		return d, c, true, nil
+	case io.EOF, io.ErrUnexpectedEOF:
+		return d, c, false, nil
+	}
+	return d, c, false, err
+}
 
-			s := bytes.Split(d, []byte{'\n'})
+// Decode data and split it in lines.
+//
+// Note: callers should lock!
+func (f *Follower) process(d []byte) []Data {
+	f.count(func(s *Stats) { s.Bytes, s.LastRead = s.Bytes+int64(len(d)), f.readAt })
+	var data []Data
+	if f.Encoding != nil {
+		var err error
+		d, err = f.decode(d)
+		if err != nil {
+			data = append(data, Data{Err: err})
+		}
+	}
+	if len(data) == 0 {
+		return f.lines(d)
+	}
+	return append(data, f.lines(d)...)
+}
 
-			// If the last bit of data doesn't end with a newline then seek back
-			// so we read it again on the next write event.
-			if len(s[len(s)-1]) != 0 {
-				seek := len(s[len(s)-1])
-				f.fp.Seek(int64(-seek), io.SeekCurrent)
+// Send the partial line and Multiline record that are waiting for more data,
+// at the end of a NoFollow read or a rotated file.
+//
+// Note: callers should lock!
+func (f *Follower) flushPartial() []Data {
+	var data []Data
+	if len(f.partial) > 0 {
+		data = f.lines([]byte{'\n'})
+		f.offset-- // The newline isn't in the file.
+	}
+	return append(data, f.flushMultiline()...)
+}
+
+// Send a line, unless Stop was called.
+func (f *Follower) send(d Data) { f.sendUntil(d, f.done) }
+
+// Send a line even if Stop was called, for the final io.EOF.
+func (f *Follower) sendFinal(d Data) { f.sendUntil(d, nil) }
+
+func (f *Follower) sendUntil(d Data, stop <-chan struct{}) {
+	if f.prepare(&d, stop) {
+		f.deliver(d, stop)
+	} else {
+		d.Release()
+	}
+	if f.stopAfter {
+		f.Stop()
+	}
+}
+
+// Send lines, in batches of up to Batch lines if it's set.
+func (f *Follower) sendAll(data []Data) {
+	if f.Batch <= 0 {
+		for i, d := range data {
+			f.send(d)
+			if f.stopAfter {
+				f.skip(data[i+1:])
 			}
-			f.fpMu.Unlock()
-			s = s[:len(s)-1]
+			if f.stopped() {
+				return
+			}
+		}
+		return
+	}
 
-			for _, ss := range s {
-				f.Data <- Data{Bytes: ss}
+	var b Data
+	for i, d := range data {
+		if f.stopped() {
+			return
+		}
+		if !f.prepare(&d, f.done) {
+			d.Release()
+		} else if d.Err != nil {
+			if b.Batch != nil {
+				f.deliver(b, f.done)
+				b = Data{}
+			}
+			f.deliver(d, f.done)
+		} else {
+			if b.Batch == nil {
+				n := f.Batch
+				if len(data) < n {
+					n = len(data)
+				}
+				b = Data{Name: d.Name, Offset: d.Offset, Line: d.Line, ReadAt: d.ReadAt, Batch: make([][]byte, 0, n)}
+			}
+			b.Batch = append(b.Batch, d.Bytes)
+			b.Long = b.Long || d.Long
+			if b.chunk == nil {
+				b.chunk = d.chunk
+			} else {
+				d.Release() // Lines from one read share a chunk, and b holds it.
+			}
+			if len(b.Batch) >= f.Batch {
+				f.deliver(b, f.done)
+				b = Data{}
 			}
 		}
+		if f.stopAfter {
+			f.skip(data[i+1:])
+			break
+		}
+	}
+	if b.Batch != nil {
+		f.deliver(b, f.done)
+	}
+	if f.stopAfter {
+		f.Stop()
+	}
+}
 
-		// File got deleted or moved; attempt to reopen.
-		if e.Op&fsnotify.Remove == fsnotify.Remove || e.Op&fsnotify.Rename == fsnotify.Rename {
-			if f.Retry == 0 {
-				f.Data <- Data{Err: errors.New("follow: file went away")}
-				f.Stop()
+// Filter and annotate a line before it's sent, and report if it should be
+// sent.
+func (f *Follower) prepare(d *Data, stop <-chan struct{}) bool {
+	d.Name = f.name
+	if d.Err == nil && f.Since != nil && !f.since(*d) {
+		return false
+	}
+	if d.Err == nil && f.StopOn != nil && f.StopOn.Match(d.Bytes) {
+		f.stopAfter = true
+	}
+	if d.Err == nil && f.MaxAge > 0 && !d.Timestamp.IsZero() && time.Since(d.Timestamp) > f.MaxAge {
+		return false
+	}
+	if d.Err == nil && f.Filter != nil && !f.Filter.keep(*d) {
+		f.count(func(s *Stats) { s.Filtered++ })
+		return false
+	}
+	if d.Err == nil && f.Sample != nil && !f.Sample.keep(&f.sampled) {
+		return false
+	}
+	if d.Err == nil && f.TraceID != nil {
+		d.TraceID = f.TraceID(*d)
+	}
+	if d.Err == nil && !f.transform(d) {
+		return false
+	}
+	if d.Err == nil && f.Dedup != nil && f.Dedup.dup(&f.dedup, d.Bytes, time.Now()) {
+		return false
+	}
+	if d.Err == nil && !f.budget(*d) {
+		return false
+	}
+	if d.Err == nil {
+		for _, a := range f.Alerts {
+			a.run(f.alertState(a), *d)
+		}
+	}
+	if d.Err != nil && d.Err != io.EOF {
+		f.count(func(s *Stats) { s.Errors++ })
+		if f.RenderError != nil {
+			d.Err = renderedError{msg: f.RenderError(d.Err), err: d.Err}
+		}
+		if f.OnError != nil {
+			f.OnError(d.Err)
+		}
+		if f.Errors != nil {
+			select {
+			case f.Errors <- d.Err:
+			case <-stop:
+			}
+			if d.Bytes == nil {
 				return false
 			}
+		}
+	}
+	if d.ReadAt.IsZero() {
+		d.ReadAt = time.Now()
+	}
+	return true
+}
 
-			f.fpMu.Lock()
-			defer f.fpMu.Unlock()
-			f.fp.Close()
-
-			// Try a few times with a very short sleep; most of the time this is
-			// something like Vim writing to the file; we don't need to wait a
-			// full second for that.
-			for i := 0; i < 10; i++ {
-				err := f.openFile(true)
-				if err == nil {
-					return true
-				}
-				time.Sleep(25 * time.Millisecond)
+// Send on the Data channel, according to Rate and Overflow.
+//
+// A select with both a send and stop picks at random if both are ready, so
+// check stop before every send; otherwise a buffered Data may still get lines
+// after Stop.
+func (f *Follower) deliver(d Data, stop <-chan struct{}) {
+	if isClosed(stop) {
+		d.Release()
+		return
+	}
+	if d.Err == nil && f.Rate != nil {
+		if wait := f.Rate.delay(&f.rate, d, time.Now()); wait > 0 {
+			t := time.NewTimer(wait)
+			select {
+			case <-t.C:
+			case <-stop:
+				t.Stop()
+				return
 			}
+		}
+		if isClosed(stop) {
+			d.Release()
+			return
+		}
+	}
+	if d.Err != nil || f.Overflow == OverflowBlock {
+		select {
+		case f.Data <- d:
+			f.sent(d)
+		case <-stop:
+		}
+		return
+	}
 
-			wait := f.Retry
-			forever := f.Retry == -1
-			for {
-				if !forever {
-					if wait <= 0 {
-						break
-					}
-					wait -= 1 * time.Second
-				}
+	for {
+		if isClosed(stop) {
+			d.Release()
+			return
+		}
+		select {
+		case f.Data <- d:
+			f.sent(d)
+			if f.dropping > 0 {
+				f.event(EventDrop)
+				f.dropping = 0
+			}
+			return
+		default:
+		}
+		if f.Overflow == OverflowDropNewest || cap(f.Data) == 0 {
+			f.drop(d)
+			return
+		}
+		select {
+		case old := <-f.Data:
+			f.drop(old)
+		default:
+		}
+	}
+}
 
-				time.Sleep(1 * time.Second)
+// Count d for MaxLines and MaxBytes, and report if there's room for it. The
+// Follower stops once either is reached.
+func (f *Follower) budget(d Data) bool {
+	if f.MaxLines <= 0 && f.MaxBytes <= 0 {
+		return true
+	}
+	n := int64(len(d.Bytes))
+	if f.MaxBytes > 0 && f.budgetBytes+n > f.MaxBytes {
+		f.budgetHit, f.stopAfter, f.skipFrom = true, true, d.Offset
+		return false
+	}
+	f.budgetLines++
+	f.budgetBytes += n
+	if f.budgetLines == f.MaxLines || f.budgetBytes == f.MaxBytes {
+		f.budgetHit, f.stopAfter, f.skipFrom = true, true, -1
+	}
+	return true
+}
 
-				err := f.openFile(true)
-				if err == nil {
-					return true
-				}
+// Record the rest of the file as skipped, if MaxLines or MaxBytes was reached;
+// rest are the lines after the last one that was sent.
+func (f *Follower) skip(rest []Data) {
+	if !f.budgetHit || f.fp == nil {
+		return
+	}
+	pos := f.skipFrom
+	if pos < 0 {
+		pos = f.offset
+		if len(rest) > 0 {
+			pos = rest[0].Offset
+		}
+	}
+	if st, err := f.fp.Stat(); err == nil && st.Size() > pos {
+		f.count(func(s *Stats) { s.Skipped = st.Size() - pos })
+	}
+	f.info("limit reached", "lines", f.budgetLines, "bytes", f.budgetBytes)
+}
+
+func (f *Follower) sent(d Data) {
+	if d.Err == nil || d.Bytes != nil {
+		f.count(func(s *Stats) { s.Lines += int64(len(d.batch())) })
+	}
+}
+
+func (f *Follower) drop(d Data) {
+	d.Release()
+	n := int64(len(d.batch()))
+	atomic.AddInt64(f.dropped, n)
+	f.dropping += n
+}
+
+// Dropped returns the number of lines that were dropped because the Data
+// channel was full; see Overflow.
+func (f *Follower) Dropped() int64 { return atomic.LoadInt64(f.dropped) }
+
+// Reset the read state, for example after the file got truncated.
+//
+// Note: callers should lock!
+func (f *Follower) reset() {
+	f.long, f.partial, f.undecoded, f.record, f.offset = false, nil, nil, nil, 0
+	if f.ResetLines {
+		f.lineno = 0
+	}
+	if f.Encoding != nil {
+		f.Encoding.Reset()
+	}
+}
+
+// Split data in lines.
+//
+// Note: callers should lock!
+func (f *Follower) lines(d []byte) []Data {
+	if len(f.partial) > 0 {
+		d = append(f.partial, d...)
+		f.partial = nil
+	}
+	pos := f.offset
+
+	// Skip the rest of a truncated line.
+	if f.long && f.LongLines == LongTruncate {
+		i := bytes.IndexByte(d, '\n')
+		if i == -1 {
+			f.offset += int64(len(d))
+			return nil
+		}
+		d, f.long = d[i+1:], false
+		pos += int64(i + 1)
+		f.lineno++
+	}
+
+	// Split off the last bit after the final newline; the lines are split
+	// with IndexByte below, rather than bytes.Split which allocates a slice
+	// for every read.
+	last := d
+	if i := bytes.LastIndexByte(d, '\n'); i > -1 {
+		last, d = d[i+1:], d[:i+1]
+	} else {
+		d = nil
+	}
+
+	// If the last bit of data doesn't end with a newline then keep it so we
+	// can prepend it to the data from the next write event, unless it's too
+	// long.
+	var long []byte
+	if len(last) != 0 {
+		if f.MaxLineLen > 0 && len(last) > f.MaxLineLen {
+			long = last
+			if f.LongLines == LongSplit {
+				keep := len(last) % f.MaxLineLen
+				long = last[:len(last)-keep]
+				f.partial = append([]byte(nil), last[len(last)-keep:]...)
 			}
-			f.Data <- Data{Err: errors.New("follow: file went away and can't reopen")}
-			f.Stop()
-			return false
+		} else {
+			f.partial = append([]byte(nil), last...)
+		}
+	}
+
+	data := make([]Data, 0, bytes.Count(d, []byte{'\n'})+1)
+	for len(d) > 0 {
+		i := bytes.IndexByte(d, '\n')
+		l := d[:i]
+		d = d[i+1:]
+
+		f.lineno++
+		line := Data{Bytes: l, Offset: pos, Line: f.lineno, ReadAt: f.readAt}
+		pos += int64(len(l) + 1)
+		if f.StripANSI {
+			line.Bytes = stripANSI(l)
+		}
+		if f.Severity != nil && !f.Severity.match(&f.severity, line.Bytes) {
+			continue
+		}
+		if d, ok := f.assemble(line); ok {
+			data = f.appendLine(data, d)
 		}
+		f.long = false
+	}
+	f.offset = pos
+	if long != nil {
+		f.longLine(pos)
+		f.long = true
+		data = f.appendLine(data, Data{Bytes: long, Offset: pos, Line: f.lineno + 1, ReadAt: f.readAt})
+		f.offset += int64(len(long))
+		if f.LongLines == LongTruncate {
+			f.offset += int64(len(last) - len(long))
+		}
+	}
+	return data
+}
+
+// Assemble a record from a line; this returns false if the record continues on
+// the next line.
+//
+// Note: callers should lock!
+func (f *Follower) assemble(line Data) (Data, bool) {
+	switch {
+	case f.CSV != 0:
+		return f.csvRecord(line)
+	case f.Multiline != nil:
+		d, ok := f.multiline(line)
+		if ok && f.Parser != nil {
+			return f.parse(d)
+		}
+		return d, ok
+	case f.Parser != nil:
+		return f.parse(line)
+	}
+	return line, true
+}
+
+// Keep a pending record, unless it's longer than MaxLineLen.
+func (f *Follower) keep(d Data) bool {
+	if f.MaxLineLen > 0 && len(d.Bytes) > f.MaxLineLen {
+		f.record = nil
+		return false
+	}
+	if f.record == nil {
+		d.Bytes = append([]byte(nil), d.Bytes...)
+		f.record = &d
 	}
 	return true
 }
+
+// Send EventLong for a line longer than MaxLineLen at off, unless it's the
+// rest of a line that was already long.
+func (f *Follower) longLine(off int64) {
+	if !f.long {
+		f.longAt = off
+		f.event(EventLong)
+	}
+}
+
+func (f *Follower) appendLine(data []Data, d Data) []Data {
+	l := d.Bytes
+	if f.MaxLineLen <= 0 || len(l) <= f.MaxLineLen {
+		return append(data, f.newData(d, f.long))
+	}
+
+	f.longLine(d.Offset)
+	if f.LongLines == LongTruncate {
+		d.Bytes = l[:f.MaxLineLen]
+		if f.LongMarker != "" {
+			n := f.MaxLineLen - len(f.LongMarker)
+			if n < 0 {
+				n = 0
+			}
+			d.Bytes = append(append(make([]byte, 0, n+len(f.LongMarker)), l[:n]...), f.LongMarker...)
+		}
+		return append(data, f.newData(d, true))
+	}
+	for len(l) > f.MaxLineLen {
+		d.Bytes = l[:f.MaxLineLen]
+		data = append(data, f.newData(d, true))
+		l = l[f.MaxLineLen:]
+		d.Offset += int64(f.MaxLineLen)
+	}
+	if len(l) > 0 {
+		d.Bytes = l
+		data = append(data, f.newData(d, true))
+	}
+	return data
+}
+
+func (f *Follower) newData(d Data, long bool) Data {
+	d.Long = long
+	if f.InvalidUTF8 != UTF8Raw && !utf8.Valid(d.Bytes) {
+		switch f.InvalidUTF8 {
+		case UTF8Replace:
+			d.Bytes = bytes.ToValidUTF8(d.Bytes, []byte("\uFFFD"))
+		case UTF8Error:
+			d.Err = ErrInvalidUTF8
+		}
+	}
+	if f.CSV != 0 && d.Err == nil {
+		d.Columns, d.Err = parseCSV(d.Bytes, f.CSV)
+	}
+	return d
+}
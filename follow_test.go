@@ -2,21 +2,49 @@ package follow
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
+	"runtime"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
-func start(ctx context.Context, t *testing.T) (Follower, string, chan []string) {
+func start(ctx context.Context, t *testing.T) (*Follower, string, chan []string) {
+	return startWith(ctx, t, nil)
+}
+
+func startWith(ctx context.Context, t *testing.T, opt func(*Follower)) (*Follower, string, chan []string) {
+	f, tmp, data := startData(ctx, t, opt)
+
+	var ret = make(chan []string)
+	go func() {
+		var lines []string
+		for _, d := range <-data {
+			lines = append(lines, string(d.Bytes))
+		}
+		ret <- lines
+	}()
+	return f, tmp, ret
+}
+
+// Like startWith, but return the full Data.
+func startData(ctx context.Context, t *testing.T, opt func(*Follower)) (*Follower, string, chan []Data) {
 	tmp := filepath.Join(t.TempDir(), "f")
 	touch(t, tmp)
 
 	f := New()
+	if opt != nil {
+		opt(&f)
+	}
 	go func() {
 		err := f.Start(ctx, tmp)
 		if err != nil {
@@ -25,23 +53,23 @@ func start(ctx context.Context, t *testing.T) (Follower, string, chan []string)
 	}()
 	<-f.Ready
 
-	var ret = make(chan []string)
+	var ret = make(chan []Data)
 	go func() {
-		var lines []string
+		var data []Data
 		for {
-			data := <-f.Data
-			if data.Err != nil {
-				if data.Err == io.EOF {
+			d := <-f.Data
+			if d.Err != nil {
+				if d.Err == io.EOF {
 					break
 				}
-				panic(data.Err)
+				panic(d.Err)
 			}
-			lines = append(lines, string(data.Bytes))
+			data = append(data, d)
 		}
-		ret <- lines
+		ret <- data
 	}()
 
-	return f, tmp, ret
+	return &f, tmp, ret
 }
 
 func write(t *testing.T, tmp string, lines ...string) []string {
@@ -66,6 +94,23 @@ func write(t *testing.T, tmp string, lines ...string) []string {
 	return lines
 }
 
+// Append a string without a newline.
+func appendString(t *testing.T, tmp, s string) {
+	fp, err := os.OpenFile(tmp, os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = fp.WriteString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = fp.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+}
+
 func touch(t *testing.T, tmp string) {
 	fp, err := os.Create(tmp)
 	if err != nil {
@@ -217,8 +262,1002 @@ func TestFollow(t *testing.T) {
 		}
 	})
 
-	// TODO: other edge cases:
-	// - Directory disappears/moves?
+	// Long lines get truncated.
+	t.Run("long_truncate", func(t *testing.T) {
+		f, tmp, lines := startWith(context.Background(), t, func(f *Follower) {
+			f.MaxLineLen = 10
+		})
+		write(t, tmp, "short", strings.Repeat("x", 25), "after")
+		appendString(t, tmp, strings.Repeat("y", 15))
+		write(t, tmp, "yyy", "end")
+
+		f.Stop()
+		got := <-lines
+		want := []string{"short", "xxxxxxxxxx", "after", "yyyyyyyyyy", "end"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+
+	// Long lines get split.
+	t.Run("long_split", func(t *testing.T) {
+		f, tmp, lines := startWith(context.Background(), t, func(f *Follower) {
+			f.MaxLineLen = 10
+			f.LongLines = LongSplit
+		})
+		write(t, tmp, "short", strings.Repeat("x", 25), "after")
+		appendString(t, tmp, strings.Repeat("y", 15))
+		write(t, tmp, "yyy", "end")
+
+		f.Stop()
+		got := <-lines
+		want := []string{"short", "xxxxxxxxxx", "xxxxxxxxxx", "xxxxx", "after",
+			"yyyyyyyyyy", "yyyyyyyy", "end"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+
+	// Truncated lines get LongMarker.
+	t.Run("long_marker", func(t *testing.T) {
+		f, tmp, lines := startWith(context.Background(), t, func(f *Follower) {
+			f.MaxLineLen = 10
+			f.LongMarker = "…"
+		})
+		write(t, tmp, "short", strings.Repeat("x", 25), strings.Repeat("y", 10))
+
+		f.Stop()
+		got := <-lines
+		want := []string{"short", "xxxxxxx…", "yyyyyyyyyy"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+
+	// Name is set on every line, also for Filter and other callbacks.
+	t.Run("name", func(t *testing.T) {
+		var names []string
+		f, tmp, data := startData(context.Background(), t, func(f *Follower) {
+			f.Filter = &Filter{Cond: func(d Data) bool {
+				names = append(names, d.Name)
+				return true
+			}}
+		})
+		write(t, tmp, "one", "two")
+
+		f.Stop()
+		for _, d := range <-data {
+			if d.Name != tmp {
+				t.Errorf("wrong name: %q", d.Name)
+			}
+		}
+		if want := []string{tmp, tmp}; !reflect.DeepEqual(names, want) {
+			t.Errorf("names in Filter: %q; want %q", names, want)
+		}
+	})
+
+	// Offset is set on every line.
+	t.Run("offset", func(t *testing.T) {
+		tmp := filepath.Join(t.TempDir(), "f")
+		err := os.WriteFile(tmp, []byte("existing\n"), 0666)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		f := New()
+		f.MaxLineLen = 4
+		f.LongLines = LongSplit
+		stop := f.Stop
+		go f.Start(context.Background(), tmp)
+		<-f.Ready
+
+		write(t, tmp, "one", "two")
+		appendString(t, tmp, "par")
+		write(t, tmp, "tial")
+
+		var got []int64
+		for i := 0; i < 4; i++ {
+			got = append(got, (<-f.Data).Offset)
+		}
+		go stop()
+		<-f.Data
+
+		want := []int64{9, 13, 17, 21}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("\ngot:  %v\nwant: %v", got, want)
+		}
+	})
+
+	// ReadAt is set on every line.
+	t.Run("read_at", func(t *testing.T) {
+		f, tmp, data := startData(context.Background(), t, nil)
+		before := time.Now()
+		write(t, tmp, "one", "two")
+		after := time.Now()
+
+		f.Stop()
+		for _, d := range <-data {
+			if d.ReadAt.Before(before) || d.ReadAt.After(after) {
+				t.Errorf("wrong time: %s not between %s and %s", d.ReadAt, before, after)
+			}
+		}
+	})
+
+	// Line numbers.
+	for _, reset := range []bool{false, true} {
+		t.Run(fmt.Sprintf("line_reset_%t", reset), func(t *testing.T) {
+			f, tmp, data := startData(context.Background(), t, func(f *Follower) {
+				f.MaxLineLen = 3
+				f.ResetLines = reset
+			})
+			write(t, tmp, "one", "two")
+			appendString(t, tmp, "long line")
+			write(t, tmp, "!", "three")
+			err := os.Truncate(tmp, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			write(t, tmp, "x")
+
+			f.Stop()
+			var got []int64
+			for _, d := range <-data {
+				got = append(got, d.Line)
+			}
+			want := []int64{1, 2, 3, 4, 5}
+			if reset {
+				want[4] = 1
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("\ngot:  %v\nwant: %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDirGone(t *testing.T) {
+	for _, tt := range []struct {
+		mv    bool
+		watch Watch
+	}{{false, WatchDir}, {true, WatchDir}, {false, WatchFile}, {true, WatchFile}} {
+		mv := tt.mv
+		t.Run(fmt.Sprintf("mv=%t/watch=%d", mv, tt.watch), func(t *testing.T) {
+			f, tmp, lines := startWith(context.Background(), t, func(f *Follower) {
+				f.Retry = -1
+				f.retryInterval = 10 * time.Millisecond
+				f.Watch = tt.watch
+			})
+			dir := filepath.Dir(tmp)
+			want := write(t, tmp, "before")
+
+			var err error
+			if mv {
+				err = os.Rename(dir, dir+".old")
+			} else {
+				err = os.RemoveAll(dir)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(50 * time.Millisecond)
+			err = os.Mkdir(dir, 0777)
+			if err != nil {
+				t.Fatal(err)
+			}
+			touch(t, tmp)
+			want = append(want, write(t, tmp, "after")...)
+			time.Sleep(100 * time.Millisecond)
+			want = append(want, write(t, tmp, "watched")...)
+			if mv {
+				write(t, filepath.Join(dir+".old", "f"), "old")
+			}
+
+			f.Stop()
+			got := <-lines
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("\ngot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestReplace(t *testing.T) {
+	for _, tt := range []struct {
+		readRotated bool
+		watch       Watch
+	}{{false, WatchDir}, {true, WatchDir}, {false, WatchFile}, {true, WatchFile}} {
+		readRotated := tt.readRotated
+		t.Run(fmt.Sprintf("%t/watch=%d", readRotated, tt.watch), func(t *testing.T) {
+			var events chan Event
+			f, tmp, lines := startWith(context.Background(), t, func(f *Follower) {
+				f.ReadRotated = readRotated
+				f.Watch = tt.watch
+				events = make(chan Event, 10)
+				f.Events = events
+			})
+			want := write(t, tmp, "one")
+			appendString(t, tmp, "partial")
+			if readRotated {
+				want = append(want, "partial")
+			}
+
+			// Write a new file and rename it over the old one.
+			err := os.WriteFile(tmp+".new", []byte("two\nthree\n"), 0666)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = os.Rename(tmp+".new", tmp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(50 * time.Millisecond)
+			want = append(want, "two", "three")
+			want = append(want, write(t, tmp, "four")...)
+
+			f.Stop()
+			got := <-lines
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("\ngot:  %q\nwant: %q", got, want)
+			}
+
+			var kinds []EventKind
+			for len(events) > 0 {
+				kinds = append(kinds, (<-events).Kind)
+			}
+			if want := []EventKind{EventOpen, EventRotate, EventReappear}; !reflect.DeepEqual(kinds, want) {
+				t.Errorf("events: %s; want %s", kinds, want)
+			}
+		})
+	}
+}
+
+func TestIsDir(t *testing.T) {
+	dir := t.TempDir()
+	f := New()
+	err := f.Start(context.Background(), dir)
+	if !errors.Is(err, ErrIsDir) {
+		t.Fatalf("wrong error: %v", err)
+	}
+
+	tmp := filepath.Join(dir, "f")
+	touch(t, tmp)
+	f = New()
+	f.Retry = -1
+	f.retryInterval = 10 * time.Millisecond
+	err = f.Go(context.Background(), tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Remove(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Mkdir(tmp, 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := <-f.Data; !errors.Is(d.Err, ErrIsDir) {
+		t.Fatalf("wrong error: %v", d.Err)
+	}
+
+	err = os.Remove(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	touch(t, tmp)
+	time.Sleep(50 * time.Millisecond)
+	write(t, tmp, "back")
+	if d := <-f.Data; d.Err != nil || d.String() != "back" {
+		t.Errorf("got %q, %v", d, d.Err)
+	}
+	f.stopAndDrain()
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	t.Run("replace", func(t *testing.T) {
+		f, tmp, lines := startWith(context.Background(), t, func(f *Follower) {
+			f.InvalidUTF8 = UTF8Replace
+		})
+		write(t, tmp, "ok", "bad\xff\xfe", "ök")
+
+		f.Stop()
+		got := <-lines
+		want := []string{"ok", "bad\uFFFD", "ök"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		tmp := filepath.Join(t.TempDir(), "f")
+		touch(t, tmp)
+
+		f := New()
+		f.InvalidUTF8 = UTF8Error
+		stop := f.Stop
+		go f.Start(context.Background(), tmp)
+		<-f.Ready
+
+		write(t, tmp, "ok", "bad\xff")
+		if d := <-f.Data; d.Err != nil || d.String() != "ok" {
+			t.Errorf("wrong data: %#v", d)
+		}
+		if d := <-f.Data; d.Err == nil || d.String() != "bad\xff" {
+			t.Errorf("wrong data: %#v", d)
+		}
+		go stop()
+		<-f.Data
+	})
+}
+
+func TestFastRetry(t *testing.T) {
+	tests := []struct {
+		n    int
+		wait time.Duration
+		slow bool
+	}{
+		{0, 0, false},
+		{-1, 0, true},
+		{2, 5 * time.Millisecond, true},
+		{20, 5 * time.Millisecond, false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d_%s", tt.n, tt.wait), func(t *testing.T) {
+			f, tmp, lines := startWith(context.Background(), t, func(f *Follower) {
+				f.Retry = -1
+				f.FastRetry, f.FastRetryInterval = tt.n, tt.wait
+				f.retryInterval = 300 * time.Millisecond
+				f.Events = make(chan Event, 10)
+			})
+
+			err := os.Remove(tmp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(30 * time.Millisecond)
+			touch(t, tmp)
+
+			var gone time.Time
+			for e := range f.Events {
+				if e.Kind == EventRemove {
+					gone = e.Time
+				}
+				if e.Kind == EventReappear {
+					took := e.Time.Sub(gone)
+					if slow := took >= 300*time.Millisecond; slow != tt.slow {
+						t.Errorf("reopened after %s", took)
+					}
+					break
+				}
+			}
+			f.Stop()
+			<-lines
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	run := func(t *testing.T, useCancel bool) []Event {
+		tmp := filepath.Join(t.TempDir(), "f")
+		touch(t, tmp)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		f := New()
+		f.Retry = -1
+		f.Events = make(chan Event, 100)
+		f.retryInterval, f.waitingInterval = 10*time.Millisecond, 50*time.Millisecond
+		stop := f.Stop
+		if useCancel {
+			stop = cancel
+		}
+		done := make(chan error)
+		go func() { done <- f.Start(ctx, tmp) }()
+		<-f.Ready
+
+		err := os.Remove(tmp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(500 * time.Millisecond)
+
+		go stop()
+		if d := <-f.Data; d.Err != io.EOF {
+			t.Errorf("wrong error: %v", d.Err)
+		}
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Start didn't return")
+		}
+
+		var events []Event
+		for len(f.Events) > 0 {
+			events = append(events, <-f.Events)
+		}
+		return events
+	}
+
+	// Retry forever should still stop when asked.
+	t.Run("cancel", func(t *testing.T) {
+		run(t, true)
+	})
+	t.Run("stop", func(t *testing.T) {
+		run(t, false)
+	})
+	t.Run("waiting", func(t *testing.T) {
+		var n, attempt int
+		for _, e := range run(t, false) {
+			if e.Kind != EventWaiting {
+				continue
+			}
+			n++
+			if e.Attempt <= attempt {
+				t.Errorf("attempt didn't increase: %d → %d", attempt, e.Attempt)
+			}
+			attempt = e.Attempt
+			if !errors.Is(e.Err, fs.ErrNotExist) {
+				t.Errorf("wrong error: %v", e.Err)
+			}
+			if e.Waited <= 0 {
+				t.Errorf("waited is %s", e.Waited)
+			}
+		}
+		if n < 2 {
+			t.Errorf("only %d EventWaiting events", n)
+		}
+	})
+
+	// Give up after Retry.
+	t.Run("give_up", func(t *testing.T) {
+		tmp := filepath.Join(t.TempDir(), "f")
+		touch(t, tmp)
+
+		f := New()
+		f.Retry = 50 * time.Millisecond
+		f.retryInterval = 10 * time.Millisecond
+		done := make(chan error)
+		go func() { done <- f.Start(context.Background(), tmp) }()
+		<-f.Ready
+
+		err := os.Remove(tmp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := <-f.Data; !errors.Is(d.Err, ErrCannotReopen) {
+			t.Errorf("wrong error: %v", d.Err)
+		}
+		if d := <-f.Data; d.Err != io.EOF {
+			t.Errorf("wrong error: %v", d.Err)
+		}
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Start didn't return")
+		}
+	})
+}
+
+func TestStop(t *testing.T) {
+	t.Run("twice", func(t *testing.T) {
+		f, _, lines := start(context.Background(), t)
+		f.Stop()
+		f.Stop()
+		<-lines
+		f.Stop()
+	})
+
+	t.Run("before_start", func(t *testing.T) {
+		tmp := filepath.Join(t.TempDir(), "f")
+		touch(t, tmp)
+
+		f := New()
+		f.Stop()
+		done := make(chan error)
+		go func() { done <- f.Start(context.Background(), tmp) }()
+		if d := <-f.Data; d.Err != io.EOF {
+			t.Errorf("wrong error: %v", d.Err)
+		}
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		f, _, lines := start(context.Background(), t)
+		for i := 0; i < 10; i++ {
+			go f.Stop()
+		}
+		<-lines
+	})
+}
+
+// Nothing should block on sending to a consumer that stopped reading.
+func TestStopBlocked(t *testing.T) {
+	for _, cancel := range []bool{false, true} {
+		t.Run(fmt.Sprintf("cancel_%t", cancel), func(t *testing.T) {
+			tmp := filepath.Join(t.TempDir(), "f")
+			touch(t, tmp)
+
+			ctx, stopCtx := context.WithCancel(context.Background())
+			defer stopCtx()
+			f := New()
+			f.Errors = make(chan error)
+			f.Events = make(chan Event)
+			f.InvalidUTF8 = UTF8Error
+			err := f.Go(ctx, tmp)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			write(t, tmp, "one", "two\xff", "three")
+			if cancel {
+				stopCtx()
+			} else {
+				f.Stop()
+			}
+
+			timeout := time.After(time.Second)
+			for {
+				select {
+				case <-timeout:
+					t.Fatal("timeout")
+				case d := <-f.Data:
+					if d.Err != io.EOF {
+						continue
+					}
+				}
+				break
+			}
+			if err := f.Wait(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// Nothing should be sent after the final io.EOF, even if Data is buffered.
+func TestStopBuffered(t *testing.T) {
+	for _, overflow := range []Overflow{OverflowBlock, OverflowDropOldest} {
+		t.Run(fmt.Sprint(overflow), func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				tmp := filepath.Join(t.TempDir(), "f")
+				err := os.WriteFile(tmp, []byte(strings.Repeat("line\n", 5000)), 0666)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				f := New()
+				f.Data = make(chan Data, 10)
+				f.Overflow = overflow
+				f.ReadSize = 64
+				f.FromStart = true
+				err = f.Go(context.Background(), tmp)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var n int
+				for d := range f.Data {
+					if n++; n == 10 {
+						f.Stop()
+					}
+					if d.Err == io.EOF {
+						break
+					}
+				}
+				select {
+				case d := <-f.Data:
+					t.Fatalf("sent after io.EOF: %v", d)
+				case <-time.After(10 * time.Millisecond):
+				}
+			}
+		})
+	}
+}
+
+func TestRenderError(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "f")
+	touch(t, tmp)
+
+	f := New()
+	f.InvalidUTF8 = UTF8Error
+	f.RenderError = func(err error) string { return "oeps: " + err.Error() }
+	stop := f.Stop
+	go f.Start(context.Background(), tmp)
+	<-f.Ready
+
+	write(t, tmp, "bad\xff")
+	d := <-f.Data
+	if d.Err == nil || d.Err.Error() != "oeps: follow: invalid UTF-8" {
+		t.Errorf("wrong error: %v", d.Err)
+	}
+	if !errors.Is(d.Err, ErrInvalidUTF8) {
+		t.Errorf("not ErrInvalidUTF8: %v", d.Err)
+	}
+	go stop()
+	if d := <-f.Data; d.Err != io.EOF {
+		t.Errorf("EOF shouldn't be rendered: %v", d.Err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "f")
+	touch(t, tmp)
+
+	f := New()
+	f.Retry = 0
+	f.InvalidUTF8 = UTF8Error
+	f.Errors = make(chan error, 10)
+	go f.Start(context.Background(), tmp)
+	<-f.Ready
+
+	write(t, tmp, "ok", "bad\xff")
+	err := os.Remove(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for d := range f.Data {
+		if d.Err == io.EOF {
+			break
+		}
+		got = append(got, fmt.Sprintf("%s %v", d, d.Err))
+	}
+	for len(f.Errors) > 0 {
+		got = append(got, (<-f.Errors).Error())
+	}
+	want := []string{"ok <nil>", "bad\xff follow: invalid UTF-8",
+		"follow: invalid UTF-8", "follow: file went away"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	tests := []struct {
+		overflow Overflow
+		size     int
+		want     []string
+		dropped  int64
+	}{
+		{OverflowDropNewest, 2, []string{"1", "2"}, 2},
+		{OverflowDropOldest, 2, []string{"3", "4"}, 2},
+		{OverflowDropOldest, 0, nil, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d_%d", tt.overflow, tt.size), func(t *testing.T) {
+			f := New()
+			f.Overflow = tt.overflow
+			f.Data = make(chan Data, tt.size)
+			for _, l := range []string{"1", "2", "3", "4"} {
+				f.send(Data{Bytes: []byte(l)})
+			}
+
+			var got []string
+			for len(f.Data) > 0 {
+				got = append(got, (<-f.Data).String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\ngot:  %q\nwant: %q", got, tt.want)
+			}
+			if d := f.Dropped(); d != tt.dropped {
+				t.Errorf("Dropped() = %d; want %d", d, tt.dropped)
+			}
+
+			// Errors are never dropped.
+			go f.send(Data{Err: errors.New("oops")})
+			if d := <-f.Data; d.Err == nil || d.Err.Error() != "oops" {
+				t.Errorf("wrong error: %v", d.Err)
+			}
+		})
+	}
+}
+
+func TestReadSize(t *testing.T) {
+	f, tmp, data := startData(context.Background(), t, func(f *Follower) {
+		f.ReadSize = 4
+	})
+	appendString(t, tmp, "hello\nworld\n\nagain\n")
+	f.Stop()
+
+	var got []string
+	for _, d := range <-data {
+		got = append(got, fmt.Sprintf("%d:%d %s", d.Line, d.Offset, d))
+	}
+	want := []string{"1:0 hello", "2:6 world", "3:12 ", "4:13 again"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	f, tmp, data := startData(context.Background(), t, func(f *Follower) {
+		f.Batch = 2
+	})
+	appendString(t, tmp, "1\n2\n3\n4\n5\n")
+	f.Stop()
+
+	var got []string
+	for i, d := range <-data {
+		if len(d.Batch) == 0 || len(d.Batch) > 2 || d.Bytes != nil {
+			t.Errorf("wrong batch: %q; Bytes: %q", d.Batch, d.Bytes)
+		}
+		if i == 0 && (d.Line != 1 || d.Offset != 0) {
+			t.Errorf("Line %d, Offset %d; want 1, 0", d.Line, d.Offset)
+		}
+		for _, l := range d.Batch {
+			got = append(got, string(l))
+		}
+	}
+	if want := []string{"1", "2", "3", "4", "5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestStopOn(t *testing.T) {
+	for _, batch := range []int{0, 2} {
+		t.Run(fmt.Sprint(batch), func(t *testing.T) {
+			f, tmp, data := startData(context.Background(), t, func(f *Follower) {
+				f.Batch = batch
+				f.StopOn = regexp.MustCompile(`Server started`)
+			})
+			appendString(t, tmp, "starting\nServer started\nafter\n")
+
+			var got []string
+			for _, d := range <-data {
+				for _, l := range d.batch() {
+					got = append(got, string(l))
+				}
+			}
+			if want := []string{"starting", "Server started"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("\ngot:  %q\nwant: %q", got, want)
+			}
+			if !f.stopped() {
+				t.Error("not stopped")
+			}
+		})
+	}
+}
+
+func TestMaxLines(t *testing.T) {
+	tests := []struct {
+		lines    int
+		bytes    int64
+		batch    int
+		want     []string
+		wantSkip int64
+	}{
+		{2, 0, 0, []string{"1", "22"}, 6},
+		{0, 3, 0, []string{"1", "22"}, 6},
+		{0, 2, 0, []string{"1"}, 9},
+		{0, 2, 2, []string{"1"}, 9},
+		{3, 100, 2, []string{"1", "22", "3"}, 4},
+		{4, 0, 0, []string{"1", "22", "3", "5"}, 0}, // "4" is filtered.
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d_%d_%d", tt.lines, tt.bytes, tt.batch), func(t *testing.T) {
+			f, tmp, data := startData(context.Background(), t, func(f *Follower) {
+				f.MaxLines, f.MaxBytes, f.Batch = tt.lines, tt.bytes, tt.batch
+				f.Filter = &Filter{Exclude: []*regexp.Regexp{regexp.MustCompile(`^4`)}}
+			})
+			appendString(t, tmp, "1\n22\n3\n4\n5\n")
+
+			var got []string
+			for _, d := range <-data {
+				for _, l := range d.batch() {
+					got = append(got, string(l))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\ngot:  %q\nwant: %q", got, tt.want)
+			}
+			if s := f.Stats().Skipped; s != tt.wantSkip {
+				t.Errorf("Skipped = %d; want %d", s, tt.wantSkip)
+			}
+		})
+	}
+}
+
+func TestNoFollow(t *testing.T) {
+	tests := []struct {
+		fromStart bool
+		want      []string
+	}{
+		{false, nil},
+		{true, []string{"one", "two", "three"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.fromStart), func(t *testing.T) {
+			tmp := filepath.Join(t.TempDir(), "f")
+			err := os.WriteFile(tmp, []byte("one\ntwo\nthree"), 0666)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f := New()
+			f.Data = make(chan Data, 10)
+			f.NoFollow, f.FromStart = true, tt.fromStart
+			err = f.Start(context.Background(), tmp)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for d := range f.Data {
+				if d.Err == io.EOF {
+					break
+				}
+				got = append(got, d.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\ngot:  %q\nwant: %q", got, tt.want)
+			}
+			if o := f.status().Offset; o != 13 {
+				t.Errorf("offset %d; want 13", o)
+			}
+		})
+	}
+}
+
+// Clear metadata that's different on every run, for comparing Data.
+func clean(data []Data) []Data {
+	for i := range data {
+		data[i].Name, data[i].Offset, data[i].Line, data[i].ReadAt = "", 0, 0, time.Time{}
+	}
+	return data
+}
+
+func BenchmarkBuffer(b *testing.B) {
+	lines := strings.Repeat(strings.Repeat("x", 100)+"\n", 1000)
+	for _, tt := range []struct{ size, batch int }{{0, 0}, {1000, 0}, {0, 100}} {
+		b.Run(fmt.Sprintf("%d_%d", tt.size, tt.batch), func(b *testing.B) {
+			tmp := filepath.Join(b.TempDir(), "f")
+			fp, err := os.Create(tmp)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer fp.Close()
+
+			f := New()
+			f.Data = make(chan Data, tt.size)
+			f.Batch = tt.batch
+			err = f.Go(context.Background(), tmp)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer f.stopAndDrain()
+
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				fp.WriteString(lines)
+				for i := 0; i < 1000; {
+					i += len((<-f.Data).batch())
+				}
+			}
+		})
+	}
+}
+
+// Write a lot of unique lines to a file that's rotated and truncated every 1,000
+// lines, check that none were lost, and report the heap size at the end; this
+// should be the same for any b.N. Use something like -benchtime=1000000x to run
+// it for longer.
+func BenchmarkSoak(b *testing.B) {
+	tmp := filepath.Join(b.TempDir(), "f")
+	fp, err := os.Create(tmp)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	f := New()
+	f.Data = make(chan Data, 1000)
+	f.Retry = -1
+	f.Dedup = &Dedup{Window: time.Hour}
+	f.Storm = &Storm{Rotations: 1000, Period: time.Hour}
+	f.Alerts = []*Alert{{Match: regexp.MustCompile(`ERROR`), Func: func(Data) {}, Dedup: time.Hour}}
+	go func() {
+		err := f.Start(context.Background(), tmp)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}()
+	<-f.Ready
+
+	var (
+		got   int64
+		errc  = make(chan error, 1)
+		done  = make(chan struct{})
+		wrote int64
+	)
+	go func() {
+		defer close(done)
+		for d := range f.Data {
+			if d.Err == io.EOF {
+				return
+			}
+			if d.Err != nil {
+				select {
+				case errc <- d.Err:
+				default:
+				}
+				continue
+			}
+			atomic.AddInt64(&got, 1)
+		}
+	}()
+	wait := func(what string, cond func() bool) {
+		for deadline := time.Now().Add(10 * time.Second); !cond(); {
+			if time.Now().After(deadline) {
+				b.Fatalf("timeout waiting for %s", what)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	// Wait until the follower read everything that was written, so that a
+	// truncate doesn't remove lines it didn't read yet.
+	caughtUp := func() { wait("lines", func() bool { return atomic.LoadInt64(&got) >= wrote }) }
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		_, err := fmt.Fprintf(fp, "ERROR line %d\nINFO line %d\n", n, n)
+		if err != nil {
+			b.Fatal(err)
+		}
+		wrote += 2
+
+		switch {
+		case n%2000 == 999: // Rotate
+			caughtUp()
+			rot := f.Stats().Rotations
+			fp.Close()
+			if err := os.Rename(tmp, tmp+".1"); err != nil {
+				b.Fatal(err)
+			}
+			if fp, err = os.Create(tmp); err != nil {
+				b.Fatal(err)
+			}
+			wait("rotate", func() bool { return f.Stats().Rotations > rot })
+		case n%2000 == 1999: // Truncate
+			caughtUp()
+			trunc := f.Stats().Truncations
+			if err := fp.Truncate(0); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := fp.Seek(0, io.SeekStart); err != nil {
+				b.Fatal(err)
+			}
+			wait("truncate", func() bool { return f.Stats().Truncations > trunc })
+		}
+	}
+	caughtUp()
+	b.StopTimer()
+
+	f.Stop()
+	<-done
+	<-f.Done()
+	fp.Close()
+	select {
+	case err := <-errc:
+		b.Fatal(err)
+	default:
+	}
+	if got != wrote {
+		b.Fatalf("read %d of %d lines", got, wrote)
+	}
+	if st := f.Stats(); st.Rotations != int64(b.N+1000)/2000 || st.Truncations != int64(b.N)/2000 {
+		b.Fatalf("%d rotations and %d truncations for %d lines", st.Rotations, st.Truncations, b.N)
+	}
+
+	var m runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&m)
+	b.ReportMetric(float64(m.HeapInuse), "heap-B")
+	runtime.KeepAlive(f)
 }
 
 func repeatSlice(s string, n int) (r []string) {
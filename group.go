@@ -0,0 +1,206 @@
+package follow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Group follows several files, and sends the lines from all of them on one
+// Data channel. Files can be added and removed while it's running, for example
+// as services come and go:
+//
+//	g := follow.NewGroup(func() follow.Follower {
+//		f := follow.New()
+//		f.Retry = -1
+//		return f
+//	})
+//	err := g.Add(ctx, "/var/log/app/a.log")
+//	...
+//	for {
+//		d := <-g.Data
+//		if d.Err == io.EOF {
+//			break
+//		}
+//		fmt.Println(d.Name, d)
+//	}
+//
+// Every file is followed by its own Follower; Data.Name tells them apart, or
+// use Subscribe to get the lines for a file on their own channel. Use Merge
+// instead to get the lines in the order they were logged.
+type Group struct {
+	Data chan Data // Lines and errors from all files that aren't subscribed to.
+
+	newf  func() Follower
+	mu    sync.Mutex
+	files map[string]*groupFile
+	subs  map[string]chan Data
+	wg    sync.WaitGroup
+	done  bool
+}
+
+type groupFile struct {
+	f    *Follower
+	out  chan Data     // Group.Data, or the channel from Subscribe; guarded by Group.mu.
+	stop chan struct{} // Closed by Remove and Stop; nothing is sent after this.
+	once sync.Once
+}
+
+func (gf *groupFile) remove() {
+	gf.once.Do(func() {
+		close(gf.stop)
+		gf.f.Stop()
+	})
+}
+
+// NewGroup creates a new Group, which creates a Follower for every file with
+// newf. If newf is nil then New is used.
+func NewGroup(newf func() Follower) *Group {
+	if newf == nil {
+		newf = New
+	}
+	return &Group{
+		Data:  make(chan Data),
+		newf:  newf,
+		files: make(map[string]*groupFile),
+		subs:  make(map[string]chan Data),
+	}
+}
+
+// Add starts following a file, and returns once it's opened and watched, or an
+// error if that failed (see Follower.Go). It does nothing if the file is
+// already followed.
+//
+// The file is removed from the Group once its Follower stops, for example
+// because Retry ran out; the error it sends is sent on Data.
+func (g *Group) Add(ctx context.Context, file string) error {
+	file = filepath.Clean(file)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.done {
+		return errors.New("follow.Group.Add: stopped")
+	}
+	if _, ok := g.files[file]; ok {
+		return nil
+	}
+
+	n := g.newf()
+	f := &n
+	err := f.Go(ctx, file)
+	if err != nil {
+		return fmt.Errorf("follow.Group.Add: %w", err)
+	}
+	gf := &groupFile{f: f, out: g.Data, stop: make(chan struct{})}
+	if ch, ok := g.subs[file]; ok {
+		gf.out = ch
+	}
+	g.files[file] = gf
+	g.wg.Add(1)
+	go g.forward(file, gf)
+	return nil
+}
+
+// Send everything from the Follower on g.Data, until it stops.
+func (g *Group) forward(file string, gf *groupFile) {
+	defer g.wg.Done()
+	for {
+		d := <-gf.f.Data
+		if d.Err == io.EOF {
+			break
+		}
+		g.mu.Lock()
+		out := gf.out
+		g.mu.Unlock()
+		select {
+		case out <- d:
+		case <-gf.stop:
+			d.Release()
+		}
+	}
+
+	g.mu.Lock()
+	if g.files[file] == gf {
+		delete(g.files, file)
+	}
+	g.mu.Unlock()
+}
+
+// Remove stops following a file. Nothing is sent for the file after this
+// returns. It does nothing if the file isn't followed.
+func (g *Group) Remove(file string) {
+	file = filepath.Clean(file)
+
+	g.mu.Lock()
+	gf, ok := g.files[file]
+	delete(g.files, file)
+	g.mu.Unlock()
+	if ok {
+		gf.remove()
+		<-gf.f.Done()
+	}
+}
+
+// Subscribe sends the lines and errors for a file on their own channel rather
+// than on Data, for example to process some files differently. The file
+// doesn't need to be added yet, and the subscription stays if it's removed and
+// added again.
+//
+// The same channel is returned if this is called more than once for a file.
+// Every channel gets an io.EOF once the Group is stopped.
+func (g *Group) Subscribe(file string) (<-chan Data, error) {
+	file = filepath.Clean(file)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.done {
+		return nil, errors.New("follow.Group.Subscribe: stopped")
+	}
+	ch, ok := g.subs[file]
+	if !ok {
+		ch = make(chan Data)
+		g.subs[file] = ch
+	}
+	if gf, ok := g.files[file]; ok {
+		gf.out = ch
+	}
+	return ch, nil
+}
+
+// Files returns the files that are currently followed, sorted by name.
+func (g *Group) Files() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	files := make([]string, 0, len(g.files))
+	for f := range g.files {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Stop following all files. This returns immediately; a final io.EOF is sent
+// on Data and every channel from Subscribe once all Followers stopped. Add and
+// Subscribe return an error after this.
+func (g *Group) Stop() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.done {
+		return
+	}
+	g.done = true
+	for _, gf := range g.files {
+		gf.remove()
+	}
+	go func() {
+		g.wg.Wait()
+		for _, ch := range g.subs {
+			go func(ch chan Data) { ch <- Data{Err: io.EOF} }(ch)
+		}
+		g.Data <- Data{Err: io.EOF}
+	}()
+}
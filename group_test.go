@@ -0,0 +1,245 @@
+package follow
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroup(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	touch(t, a)
+	touch(t, b)
+
+	g := NewGroup(nil)
+	ctx := context.Background()
+	for _, f := range []string{a, b, a} {
+		err := g.Add(ctx, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.Add(ctx, filepath.Join(dir, "nonexistent")); err == nil {
+		t.Error("no error for nonexistent file")
+	}
+	if got, want := g.Files(), []string{a, b}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Files() = %q; want %q", got, want)
+	}
+
+	recv := func(n int) []string {
+		var got []string
+		for i := 0; i < n; i++ {
+			select {
+			case d := <-g.Data:
+				if d.Err != nil {
+					t.Fatal(d.Err)
+				}
+				got = append(got, filepath.Base(d.Name)+" "+d.String())
+			case <-time.After(time.Second):
+				t.Fatalf("timeout after %q", got)
+			}
+		}
+		sort.Strings(got)
+		return got
+	}
+
+	write(t, a, "one")
+	write(t, b, "two")
+	if got, want := recv(2), []string{"a one", "b two"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+
+	g.Remove(a)
+	write(t, a, "removed")
+	write(t, b, "three")
+	if got, want := recv(1), []string{"b three"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+	if got, want := g.Files(), []string{b}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Files() = %q; want %q", got, want)
+	}
+
+	g.Stop()
+	if d := <-g.Data; d.Err != io.EOF {
+		t.Errorf("got %v; want io.EOF", d.Err)
+	}
+	if err := g.Add(ctx, a); err == nil {
+		t.Error("no error after Stop")
+	}
+}
+
+func TestGroupSubscribe(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	touch(t, a)
+	touch(t, b)
+
+	g := NewGroup(nil)
+	ctx := context.Background()
+	subA, err := g.Subscribe(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{a, b} {
+		err := g.Add(ctx, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	subB, err := g.Subscribe(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := g.Subscribe(a); again != subA {
+		t.Error("different channel for second Subscribe")
+	}
+
+	recv := func(ch <-chan Data) string {
+		select {
+		case d := <-ch:
+			if d.Err != nil {
+				return d.Err.Error()
+			}
+			return filepath.Base(d.Name) + " " + d.String()
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+			return ""
+		}
+	}
+	write(t, a, "one")
+	write(t, b, "two")
+	if got := recv(subA); got != "a one" {
+		t.Errorf("subA: %q", got)
+	}
+	if got := recv(subB); got != "b two" {
+		t.Errorf("subB: %q", got)
+	}
+
+	g.Stop()
+	for _, ch := range []<-chan Data{g.Data, subA, subB} {
+		if got := recv(ch); got != "EOF" {
+			t.Errorf("got %q; want EOF", got)
+		}
+	}
+	if _, err := g.Subscribe(a); err == nil {
+		t.Error("no error after Stop")
+	}
+}
+
+func TestGroupGone(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "f")
+	touch(t, tmp)
+
+	g := NewGroup(func() Follower {
+		f := New()
+		f.Retry = 0
+		return f
+	})
+	err := g.Add(context.Background(), tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Remove(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := <-g.Data; d.Err != ErrFileGone {
+		t.Errorf("got %v; want ErrFileGone", d.Err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if f := g.Files(); len(f) != 0 {
+		t.Errorf("Files() = %q", f)
+	}
+	g.Stop()
+	<-g.Data
+}
+
+// Options with state can be shared between the followers in a Group; every
+// Follower keeps its own state.
+func TestGroupShared(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	touch(t, a)
+	touch(t, b)
+
+	var (
+		dedup    = &Dedup{}
+		rate     = &Rate{Lines: 100_000}
+		sample   = &Sample{Every: 2}
+		storm    = &Storm{Rotations: 100, Period: time.Hour}
+		severity = &Severity{Tokens: []string{"line"}}
+		alerts   int64
+		alert    = &Alert{
+			Match: regexp.MustCompile(`line`),
+			Func:  func(Data) { atomic.AddInt64(&alerts, 1) },
+			Dedup: time.Hour,
+		}
+	)
+	g := NewGroup(func() Follower {
+		f := New()
+		f.Dedup = dedup
+		f.Alerts = []*Alert{alert}
+		f.Rate = rate
+		f.Sample = sample
+		f.Storm = storm
+		f.Severity = severity
+		return f
+	})
+	ctx := context.Background()
+	for _, f := range []string{a, b} {
+		err := g.Add(ctx, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var lines []string
+	for i := 0; i < 200; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	write(t, a, lines...)
+	write(t, b, lines...)
+
+	got := make(map[string]int)
+	// Every other line is sent because of Sample, from both files.
+	for i := 0; i < len(lines); i++ {
+		select {
+		case d := <-g.Data:
+			if d.Err != nil {
+				t.Fatal(d.Err)
+			}
+			got[filepath.Base(d.Name)]++
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout: %v", got)
+		}
+	}
+
+	// Rotate both files, so both followers record it for Storm.
+	for _, f := range []string{a, b} {
+		err := os.Rename(f, f+".1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		touch(t, f)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	g.Stop()
+	<-g.Data
+	if got["a"] != len(lines)/2 || got["b"] != len(lines)/2 {
+		t.Errorf("got %v", got)
+	}
+	if n := atomic.LoadInt64(&alerts); n != int64(len(lines)) {
+		t.Errorf("%d alerts", n)
+	}
+}
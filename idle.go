@@ -0,0 +1,44 @@
+package follow
+
+import "time"
+
+// Reset the idle timers after reading data.
+func (f *Follower) resetIdleTimer() {
+	if f.Idle > 0 {
+		resetTimer(&f.idleTimer, f.Idle)
+	}
+	f.resetIdleStop()
+}
+
+// Reset the IdleStop timer after reading data or an event.
+func (f *Follower) resetIdleStop() {
+	if f.IdleStop > 0 {
+		resetTimer(&f.idleStopTimer, f.IdleStop)
+	}
+}
+
+func (f *Follower) idleTimeout() <-chan time.Time     { return timerC(f.idleTimer) }
+func (f *Follower) idleStopTimeout() <-chan time.Time { return timerC(f.idleStopTimer) }
+
+// Reset *t to fire after d, creating it if it's nil.
+func resetTimer(t **time.Timer, d time.Duration) {
+	if *t == nil {
+		*t = time.NewTimer(d)
+		return
+	}
+	if !(*t).Stop() {
+		select {
+		case <-(*t).C:
+		default:
+		}
+	}
+	(*t).Reset(d)
+}
+
+// Channel for a timer; nil if there's no timer, which blocks forever.
+func timerC(t *time.Timer) <-chan time.Time {
+	if t == nil {
+		return nil
+	}
+	return t.C
+}
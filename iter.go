@@ -0,0 +1,47 @@
+//go:build go1.23
+
+package follow
+
+import (
+	"context"
+	"io"
+	"iter"
+)
+
+// Lines returns an iterator over all lines, for use with range:
+//
+//	f := follow.New()
+//	err := f.Go(ctx, "file")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	for d, err := range f.Lines(ctx) {
+//		if err != nil {
+//			log.Print(err)
+//			continue
+//		}
+//		fmt.Println(d)
+//	}
+//
+// The loop ends when Stop is called or the context is cancelled. Breaking from
+// the loop calls Stop.
+func (f *Follower) Lines(ctx context.Context) iter.Seq2[Data, error] {
+	return func(yield func(Data, error) bool) {
+		for {
+			var d Data
+			select {
+			case <-ctx.Done():
+				f.stopAndDrain()
+				return
+			case d = <-f.Data:
+			}
+			if d.Err == io.EOF {
+				return
+			}
+			if !yield(d, d.Err) {
+				f.stopAndDrain()
+				return
+			}
+		}
+	}
+}
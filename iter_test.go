@@ -0,0 +1,39 @@
+//go:build go1.23
+
+package follow
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "f")
+	touch(t, tmp)
+
+	f := New()
+	err := f.Go(context.Background(), tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write(t, tmp, "one", "two", "three")
+
+	var got []string
+	for d, err := range f.Lines(context.Background()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, d.String())
+		if len(got) == 2 {
+			break
+		}
+	}
+	if want := []string{"one", "two"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+	if err := f.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
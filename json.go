@@ -0,0 +1,54 @@
+package follow
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+)
+
+// Result is a line decoded by JSON.
+type Result[T any] struct {
+	Value T
+	Data  Data  // Original line.
+	Err   error // Error from Data, or decoding error.
+}
+
+// JSON decodes every line sent on f.Data as JSON in to T.
+//
+// Decode errors are set on Result.Err, as are any errors from f.Data; the
+// value is the zero value of T in that case. The channel is closed once the
+// follower stops, or once the context is cancelled; after that f.Data is read
+// until io.EOF so that Start can return.
+func JSON[T any](ctx context.Context, f *Follower) <-chan Result[T] {
+	ch := make(chan Result[T])
+	go func() {
+		defer close(ch)
+		for {
+			var d Data
+			select {
+			case <-ctx.Done():
+				f.stopAndDrain()
+				return
+			case d = <-f.Data:
+			}
+			if d.Err == io.EOF {
+				return
+			}
+
+			r := Result[T]{Data: d, Err: d.Err}
+			if r.Err == nil {
+				r.Err = json.Unmarshal(d.Bytes, &r.Value)
+			}
+			select {
+			case ch <- r:
+			case <-ctx.Done():
+				f.stopAndDrain()
+				return
+			case <-f.done:
+				f.stopAndDrain()
+				return
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,75 @@
+package follow
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJSON(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "f")
+	touch(t, tmp)
+
+	f := New()
+	go f.Start(context.Background(), tmp)
+	<-f.Ready
+
+	type rec struct {
+		Level string `json:"level"`
+		N     int    `json:"n"`
+	}
+	ch := JSON[rec](context.Background(), &f)
+
+	write(t, tmp, `{"level":"info","n":1}`, `not json`, `{"level":"error","n":2}`)
+	var (
+		got  []rec
+		errs int
+	)
+	for i := 0; i < 3; i++ {
+		r := <-ch
+		if r.Err != nil {
+			errs++
+			continue
+		}
+		got = append(got, r.Value)
+	}
+
+	f.Stop()
+	if _, ok := <-ch; ok {
+		t.Error("channel not closed")
+	}
+
+	want := []rec{{"info", 1}, {"error", 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %v\nwant: %v", got, want)
+	}
+	if errs != 1 {
+		t.Errorf("errs: %d", errs)
+	}
+}
+
+// The goroutine shouldn't leak if nothing reads from the channel.
+func TestJSONCancel(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "f")
+	touch(t, tmp)
+
+	f := New()
+	err := f.Go(context.Background(), tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := JSON[map[string]any](ctx, &f)
+	write(t, tmp, `{"n":1}`, `{"n":2}`)
+	cancel()
+
+	select {
+	case <-f.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Start didn't return")
+	}
+	for range ch {
+	}
+}
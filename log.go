@@ -0,0 +1,23 @@
+package follow
+
+// Logger logs diagnostic messages about what a Follower is doing, such as
+// reopen attempts and why it decided a file was truncated. The arguments are
+// key/value pairs, and *slog.Logger implements this:
+//
+//	f.Log = slog.Default()
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+}
+
+func (f *Follower) debug(msg string, args ...any) {
+	if f.Log != nil {
+		f.Log.Debug(msg, append([]any{"file", f.name}, args...)...)
+	}
+}
+
+func (f *Follower) info(msg string, args ...any) {
+	if f.Log != nil {
+		f.Log.Info(msg, append([]any{"file", f.name}, args...)...)
+	}
+}
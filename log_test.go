@@ -0,0 +1,52 @@
+package follow
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testLogger struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (l *testLogger) Debug(msg string, args ...any) { l.log("DEBUG", msg, args) }
+func (l *testLogger) Info(msg string, args ...any)  { l.log("INFO", msg, args) }
+
+func (l *testLogger) log(lvl, msg string, args []any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.msgs = append(l.msgs, fmt.Sprintf("%s %s %v", lvl, msg, args[2:]))
+}
+
+func TestLog(t *testing.T) {
+	l := new(testLogger)
+	f, tmp, lines := startWith(context.Background(), t, func(f *Follower) {
+		f.Log = l
+	})
+	write(t, tmp, "one")
+	err := os.Truncate(tmp, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	write(t, tmp, "two")
+	f.Stop()
+	<-lines
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	want := []string{
+		"INFO open [offset 0]",
+		"DEBUG read position is past the end of the file; assuming it was truncated [position 4 size 0]",
+		"INFO truncate [offset 0]",
+	}
+	if !reflect.DeepEqual(l.msgs, want) {
+		t.Errorf("\ngot:  %q\nwant: %q", l.msgs, want)
+	}
+}
@@ -0,0 +1,73 @@
+package follow
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// Logfmt parses key=value pairs separated by spaces in to Record.Fields, where
+// values can be quoted with "..." and keys without a value are set to "".
+//
+// This never fails; anything that's not quite valid is parsed as well as
+// possible.
+type Logfmt struct{}
+
+func (Logfmt) Parse(l []byte) (Record, error) {
+	return Record{Fields: parseLogfmt(l)}, nil
+}
+
+func parseLogfmt(l []byte) map[string]string {
+	fields := make(map[string]string)
+	for {
+		l = bytes.TrimLeft(l, " \t")
+		if len(l) == 0 {
+			return fields
+		}
+
+		i := bytes.IndexAny(l, "= \t")
+		if i == -1 {
+			fields[string(l)] = ""
+			return fields
+		}
+		key := string(l[:i])
+		if l[i] != '=' {
+			fields[key] = ""
+			l = l[i:]
+			continue
+		}
+		l = l[i+1:]
+
+		var v string
+		if len(l) > 0 && l[0] == '"' {
+			j := 1
+			for ; j < len(l); j++ {
+				if l[j] == '\\' {
+					j++
+					continue
+				}
+				if l[j] == '"' {
+					break
+				}
+			}
+			if j >= len(l) { // No closing quote.
+				j = len(l) - 1
+			}
+
+			var err error
+			v, err = strconv.Unquote(string(l[:j+1]))
+			if err != nil {
+				v = string(bytes.Trim(l[:j+1], `"`))
+			}
+			l = l[j+1:]
+		} else {
+			j := bytes.IndexAny(l, " \t")
+			if j == -1 {
+				j = len(l)
+			}
+			v, l = string(l[:j]), l[j:]
+		}
+		if key != "" {
+			fields[key] = v
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package follow
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParseLogfmt(t *testing.T) {
+	tests := []struct {
+		in   string
+		want map[string]string
+	}{
+		{``, map[string]string{}},
+		{`a=1`, map[string]string{"a": "1"}},
+		{`a=1 b=x  c=`, map[string]string{"a": "1", "b": "x", "c": ""}},
+		{`level=info msg="hello world" n=5`, map[string]string{"level": "info", "msg": "hello world", "n": "5"}},
+		{`msg="with \"quotes\"" x=y`, map[string]string{"msg": `with "quotes"`, "x": "y"}},
+		{`debug a=b flag`, map[string]string{"debug": "", "a": "b", "flag": ""}},
+		{`msg="unterminated a=b`, map[string]string{"msg": "unterminated a=b"}},
+		{`=orphan a=b`, map[string]string{"a": "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%q", tt.in), func(t *testing.T) {
+			got := parseLogfmt([]byte(tt.in))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\ngot:  %q\nwant: %q", got, tt.want)
+			}
+		})
+	}
+}
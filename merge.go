@@ -0,0 +1,180 @@
+package follow
+
+import (
+	"container/heap"
+	"context"
+	"io"
+	"time"
+)
+
+// Merge reads lines from several Followers' Data channels and sends them
+// through this Follower in order of Data.Timestamp, like Pipe. For example to
+// follow an access and error log as one stream:
+//
+//	access, errors := follow.New(), follow.New()
+//	access.Parser, errors.Parser = myParser{}, myParser{}
+//	access.Go(ctx, "access.log")
+//	errors.Go(ctx, "error.log")
+//
+//	merged := follow.New()
+//	go merged.Merge(ctx, time.Second, access.Data, errors.Data)
+//
+// The inputs need a Parser that sets Record.Timestamp; lines without a
+// timestamp use the timestamp of the line before it from the same input (so
+// Multiline records stay together), or ReadAt if there is none. Every input is
+// expected to be in order.
+//
+// A line is sent once every input has a line waiting, so that the earliest can
+// be picked, or once it waited for window. Lines that are more than window out
+// of order are sent in the order they were read. Errors are sent through
+// as-is, right away.
+//
+// This returns once io.EOF is read from all inputs, or the context is
+// cancelled.
+func (f *Follower) Merge(ctx context.Context, window time.Duration, in ...<-chan Data) error {
+	close(f.Ready)
+	f.stopOnCancel(ctx)
+	defer func() {
+		f.Stop()
+		f.sendFinal(Data{Err: io.EOF})
+	}()
+
+	type input struct {
+		i int
+		d Data
+	}
+	var (
+		recv    = make(chan input)
+		quit    = make(chan struct{})
+		open    = len(in)
+		inputs  = make([]mergeInput, len(in))
+		pending mergeHeap
+		seq     uint64
+		timer   *time.Timer
+	)
+	defer close(quit)
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	for i, ch := range in {
+		go func(i int, ch <-chan Data) {
+			for {
+				var (
+					d  Data
+					ok bool
+				)
+				select {
+				case <-quit:
+					return
+				case d, ok = <-ch:
+				}
+				if !ok {
+					d = Data{Err: io.EOF}
+				}
+				select {
+				case <-quit:
+					return
+				case recv <- input{i, d}:
+				}
+				if d.Err == io.EOF {
+					return
+				}
+			}
+		}(i, ch)
+	}
+
+	// Send everything that's ready, and wait for the next one.
+	flush := func(now time.Time) {
+		for pending.Len() > 0 && !f.stopped() {
+			m := pending[0]
+			if now.Sub(m.at) < window && !allPending(inputs) {
+				resetTimer(&timer, window-now.Sub(m.at))
+				return
+			}
+			heap.Pop(&pending)
+			inputs[m.in].pending--
+			f.pipe(m.d)
+		}
+	}
+
+	for open > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-f.done:
+			return nil
+		case <-timerC(timer):
+			flush(time.Now())
+		case <-f.multiTimeout():
+			f.sendMultiline()
+		case r := <-recv:
+			inp := &inputs[r.i]
+			switch {
+			case r.d.Err == io.EOF:
+				inp.closed = true
+				open--
+			case r.d.Err != nil:
+				f.pipe(r.d)
+			default:
+				ts := r.d.Timestamp
+				if ts.IsZero() {
+					ts = inp.last
+				}
+				if ts.IsZero() {
+					ts = r.d.ReadAt
+				}
+				inp.last, inp.pending = ts, inp.pending+1
+				seq++
+				heap.Push(&pending, mergeLine{d: r.d, in: r.i, ts: ts, at: time.Now(), seq: seq})
+			}
+			flush(time.Now())
+		}
+	}
+	flush(time.Now())
+	f.sendMultiline()
+	return nil
+}
+
+type (
+	mergeInput struct {
+		last    time.Time // Timestamp of the last line.
+		pending int       // Lines waiting to be sent.
+		closed  bool      // Read io.EOF.
+	}
+	mergeLine struct {
+		d   Data
+		in  int       // Index of the input.
+		ts  time.Time // Timestamp to sort on.
+		at  time.Time // Time it was received.
+		seq uint64    // Order it was received, for lines with the same timestamp.
+	}
+	mergeHeap []mergeLine
+)
+
+// Report if every input that's still open has a line waiting.
+func allPending(inputs []mergeInput) bool {
+	for _, in := range inputs {
+		if !in.closed && in.pending == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (h mergeHeap) Len() int { return len(h) }
+func (h mergeHeap) Less(i, j int) bool {
+	if h[i].ts.Equal(h[j].ts) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].ts.Before(h[j].ts)
+}
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x any)   { *h = append(*h, x.(mergeLine)) }
+func (h *mergeHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,144 @@
+package follow
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	ts := func(s int) time.Time { return time.Date(2024, 5, 1, 14, 32, s, 0, time.UTC) }
+	line := func(s int, l string) Data {
+		return Data{Bytes: []byte(l), Record: Record{Timestamp: ts(s)}}
+	}
+
+	t.Run("all pending", func(t *testing.T) {
+		a, b := make(chan Data, 10), make(chan Data, 10)
+		for _, d := range []Data{line(1, "a1"), line(3, "a3"), {Bytes: []byte("  a3 continued")}, line(5, "a5"), {Err: io.EOF}} {
+			a <- d
+		}
+		for _, d := range []Data{line(2, "b2"), line(4, "b4"), line(6, "b6"), {Err: io.EOF}} {
+			b <- d
+		}
+
+		f := New()
+		f.Data = make(chan Data, 20)
+		err := f.Merge(context.Background(), time.Hour, a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := drain(f.Data)
+		want := []string{"a1", "b2", "a3", "  a3 continued", "b4", "a5", "b6"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+
+	// Offset and Line are from the file the line was read from, not counted
+	// across all inputs.
+	t.Run("position", func(t *testing.T) {
+		pos := func(s int, name string, lineno, off int64) Data {
+			d := line(s, name+fmt.Sprint(s))
+			d.Name, d.Line, d.Offset = name, lineno, off
+			return d
+		}
+		a, b := make(chan Data, 10), make(chan Data, 10)
+		for _, d := range []Data{pos(1, "a", 10, 100), pos(3, "a", 11, 103), {Err: io.EOF}} {
+			a <- d
+		}
+		for _, d := range []Data{pos(2, "b", 1, 0), pos(4, "b", 2, 3), {Err: io.EOF}} {
+			b <- d
+		}
+
+		f := New()
+		f.Data = make(chan Data, 20)
+		err := f.Merge(context.Background(), time.Hour, a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for d := range f.Data {
+			if d.Err == io.EOF {
+				break
+			}
+			got = append(got, fmt.Sprintf("%s %d:%d %s", d.Name, d.Line, d.Offset, d.Bytes))
+		}
+		want := []string{"a 10:100 a1", "b 1:0 b2", "a 11:103 a3", "b 2:3 b4"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+
+	t.Run("window", func(t *testing.T) {
+		a, b := make(chan Data), make(chan Data)
+		f := New()
+		f.Data = make(chan Data, 20)
+		go f.Merge(context.Background(), 50*time.Millisecond, a, b)
+		<-f.Ready
+
+		a <- line(2, "a2")
+		select {
+		case d := <-f.Data:
+			t.Fatalf("sent before window: %q", d.Bytes)
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		// Both inputs have a line, so the earliest can be sent.
+		b <- line(1, "b1")
+		time.Sleep(10 * time.Millisecond)
+		if n := len(f.Data); n != 1 {
+			t.Fatalf("%d lines in Data; want 1", n)
+		}
+
+		// Nothing from b for a while: a2 is sent after the window.
+		time.Sleep(50 * time.Millisecond)
+		if n := len(f.Data); n != 2 {
+			t.Fatalf("%d lines in Data; want 2", n)
+		}
+		a <- line(5, "a5")
+		close(a)
+		close(b)
+
+		got := drain(f.Data)
+		want := []string{"b1", "a2", "a5"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+
+	t.Run("multiline timeout", func(t *testing.T) {
+		a := make(chan Data)
+		f := New()
+		f.Multiline = &Multiline{Continue: MultilineJava.Continue, Timeout: 50 * time.Millisecond}
+		go f.Merge(context.Background(), time.Hour, a)
+		<-f.Ready
+
+		a <- line(1, "a1")
+		a <- Data{Bytes: []byte("\tat Foo.bar")}
+		select {
+		case d := <-f.Data:
+			if want := "a1\n\tat Foo.bar"; string(d.Bytes) != want {
+				t.Errorf("\ngot:  %q\nwant: %q", d.Bytes, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("record not sent after Timeout")
+		}
+		f.Stop()
+	})
+}
+
+func drain(ch chan Data) []string {
+	var lines []string
+	for d := range ch {
+		if d.Err == io.EOF {
+			return lines
+		}
+		lines = append(lines, string(d.Bytes))
+	}
+	return lines
+}
@@ -0,0 +1,87 @@
+// Package metrics exposes the Stats of a Follower as Prometheus metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"zgo.at/follow"
+)
+
+// Collector is a prometheus.Collector for the Stats of a Follower.
+type Collector struct {
+	f     *follow.Follower
+	descs []desc
+}
+
+type desc struct {
+	*prometheus.Desc
+	typ prometheus.ValueType
+	val func(follow.Stats) float64
+}
+
+// NewCollector creates a new Collector for f. The labels are added to every
+// metric, and can be used to tell followers apart if you register more than
+// one. For example:
+//
+//	f := follow.New()
+//	prometheus.MustRegister(metrics.NewCollector(&f, prometheus.Labels{"file": "app.log"}))
+//
+// The metrics are:
+//
+//	follow_read_bytes_total                Bytes read from the file.
+//	follow_lines_total                     Lines sent on Data.
+//	follow_errors_total                    Errors, other than io.EOF.
+//	follow_dropped_lines_total             Lines dropped by Follower.Overflow.
+//	follow_filtered_lines_total            Lines dropped by Follower.Filter.
+//	follow_reopens_total                   Times the file was reopened.
+//	follow_rotations_total                 Times the file was rotated or removed.
+//	follow_truncations_total               Times the file was truncated.
+//	follow_lag_bytes                       Bytes written to the file that weren't read yet.
+//	follow_last_read_timestamp_seconds     Time data was last read.
+func NewCollector(f *follow.Follower, labels prometheus.Labels) *Collector {
+	d := func(name, help string, typ prometheus.ValueType, val func(follow.Stats) float64) desc {
+		return desc{prometheus.NewDesc("follow_"+name, help, nil, labels), typ, val}
+	}
+	return &Collector{f: f, descs: []desc{
+		d("read_bytes_total", "Bytes read from the file.", prometheus.CounterValue,
+			func(s follow.Stats) float64 { return float64(s.Bytes) }),
+		d("lines_total", "Lines sent on Data.", prometheus.CounterValue,
+			func(s follow.Stats) float64 { return float64(s.Lines) }),
+		d("errors_total", "Errors, other than io.EOF.", prometheus.CounterValue,
+			func(s follow.Stats) float64 { return float64(s.Errors) }),
+		d("dropped_lines_total", "Lines dropped because the consumer was too slow.", prometheus.CounterValue,
+			func(s follow.Stats) float64 { return float64(s.Dropped) }),
+		d("filtered_lines_total", "Lines dropped by the filter.", prometheus.CounterValue,
+			func(s follow.Stats) float64 { return float64(s.Filtered) }),
+		d("reopens_total", "Times the file was reopened.", prometheus.CounterValue,
+			func(s follow.Stats) float64 { return float64(s.Reopens) }),
+		d("rotations_total", "Times the file was rotated or removed.", prometheus.CounterValue,
+			func(s follow.Stats) float64 { return float64(s.Rotations) }),
+		d("truncations_total", "Times the file was truncated.", prometheus.CounterValue,
+			func(s follow.Stats) float64 { return float64(s.Truncations) }),
+		d("lag_bytes", "Bytes written to the file that weren't read yet.", prometheus.GaugeValue,
+			func(s follow.Stats) float64 { return float64(s.Lag) }),
+		d("last_read_timestamp_seconds", "Time data was last read, as a Unix timestamp.", prometheus.GaugeValue,
+			func(s follow.Stats) float64 {
+				if s.LastRead.IsZero() {
+					return 0
+				}
+				return float64(s.LastRead.UnixNano()) / 1e9
+			}),
+	}}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d.Desc
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	s := c.f.Stats()
+	for _, d := range c.descs {
+		ch <- prometheus.MustNewConstMetric(d.Desc, d.typ, d.val(s))
+	}
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"zgo.at/follow"
+)
+
+func TestCollector(t *testing.T) {
+	a, b := follow.New(), follow.New()
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(NewCollector(&a, prometheus.Labels{"file": "a"}))
+	reg.MustRegister(NewCollector(&b, prometheus.Labels{"file": "b"}))
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(families) != 10 {
+		t.Errorf("got %d metrics; want 10", len(families))
+	}
+	for _, fam := range families {
+		if len(fam.Metric) != 2 {
+			t.Errorf("%s: got %d followers; want 2", fam.GetName(), len(fam.Metric))
+		}
+		for _, m := range fam.Metric {
+			if l := m.GetLabel(); len(l) != 1 || l[0].GetName() != "file" {
+				t.Errorf("%s: wrong labels: %v", fam.GetName(), l)
+			}
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package follow
+
+import (
+	"regexp"
+	"time"
+)
+
+// Multiline joins several lines in to a single record, for example for stack
+// traces. Set either Start or Continue.
+type Multiline struct {
+	// Lines matching this start a new record; all other lines are added to
+	// the current record.
+	Start *regexp.Regexp
+
+	// Lines matching this are added to the current record; all other lines
+	// start a new record.
+	Continue *regexp.Regexp
+
+	// Send the current record if no new lines were read for this long, as
+	// there's no way to know a record is complete until the next one starts.
+	//
+	// Default is 0, which means 1 second.
+	Timeout time.Duration
+}
+
+// Multiline presets for common formats.
+var (
+	// Java stack traces:
+	//
+	//	Exception in thread "main" java.lang.IllegalStateException: oh noes
+	//		at com.example.Main.run(Main.java:12)
+	//	Caused by: java.io.IOException: oops
+	//		... 3 more
+	MultilineJava = Multiline{
+		Continue: regexp.MustCompile(`^(\s+(at |\.\.\. \d+ )|\s*(Caused by|Suppressed): )`),
+	}
+
+	// Python tracebacks:
+	//
+	//	Traceback (most recent call last):
+	//	  File "x.py", line 1, in <module>
+	//	    main()
+	//	ValueError: oh noes
+	MultilinePython = Multiline{
+		Continue: regexp.MustCompile(`^(\s|[\w.]+(Error|Exception|Warning|Exit|Interrupt|Iteration)\b)`),
+	}
+
+	// Go panics:
+	//
+	//	panic: oh noes
+	//
+	//	goroutine 1 [running]:
+	//	main.main()
+	//		/home/martin/x.go:5 +0x1d
+	//	exit status 2
+	MultilineGo = Multiline{
+		Continue: regexp.MustCompile(`^(\s|$|goroutine \d+ \[|\[signal |created by |exit status \d|[\w./*()\-]+\.[\w*()\-]+\(.*\)$)`),
+	}
+)
+
+// MultilinePreset gets a multiline preset by name: "java", "python", or "go".
+func MultilinePreset(name string) (*Multiline, bool) {
+	var m Multiline
+	switch name {
+	case "java":
+		m = MultilineJava
+	case "python":
+		m = MultilinePython
+	case "go":
+		m = MultilineGo
+	default:
+		return nil, false
+	}
+	return &m, true
+}
+
+// Add a line to the current multiline record, returning the previous record
+// once a new one starts.
+//
+// Note: callers should lock!
+func (f *Follower) multiline(line Data) (Data, bool) {
+	m, l := f.Multiline, line.Bytes
+	defer f.resetMultiTimer()
+
+	if f.multi != nil && ((m.Start != nil && !m.Start.Match(l)) || (m.Continue != nil && m.Continue.Match(l))) {
+		f.multi.Bytes = append(append(f.multi.Bytes, '\n'), l...)
+		if f.MaxLineLen > 0 && len(f.multi.Bytes) > f.MaxLineLen {
+			d := *f.multi
+			f.multi = nil
+			return d, true
+		}
+		return Data{}, false
+	}
+
+	prev := f.multi
+	line.Bytes = append([]byte(nil), l...)
+	f.multi = &line
+	if prev == nil {
+		return Data{}, false
+	}
+	return *prev, true
+}
+
+func (f *Follower) resetMultiTimer() {
+	t := f.Multiline.Timeout
+	if t == 0 {
+		t = time.Second
+	}
+	resetTimer(&f.multiTimer, t)
+}
+
+// Channel for the multiline timeout; nil if there's no timer.
+func (f *Follower) multiTimeout() <-chan time.Time { return timerC(f.multiTimer) }
+
+// Send the current multiline record, after Multiline.Timeout or once the input
+// ends.
+func (f *Follower) sendMultiline() {
+	f.fpMu.Lock()
+	lines := f.flushMultiline()
+	f.fpMu.Unlock()
+
+	f.sendAll(lines)
+}
+
+// Send the current multiline record, if any.
+//
+// Note: callers should lock!
+func (f *Follower) flushMultiline() []Data {
+	if f.multi == nil {
+		return nil
+	}
+	d := *f.multi
+	f.multi = nil
+	if f.Parser != nil {
+		var ok bool
+		d, ok = f.parse(d)
+		if !ok {
+			return nil
+		}
+	}
+	return f.appendLine(nil, d)
+}
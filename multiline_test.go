@@ -0,0 +1,107 @@
+package follow
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestMultiline(t *testing.T) {
+	tests := []struct {
+		name  string
+		m     *Multiline
+		lines []string
+		want  []string
+	}{
+		{"start", &Multiline{Start: regexp.MustCompile(`^\d\d:\d\d `)},
+			[]string{"12:00 one", "  more", "12:01 two", "12:02 three", "more", "more"},
+			[]string{"12:00 one\n  more", "12:01 two", "12:02 three\nmore\nmore"}},
+		{"java", &MultilineJava,
+			[]string{
+				"INFO starting",
+				`Exception in thread "main" java.lang.IllegalStateException: oh noes`,
+				"\tat com.example.Main.run(Main.java:12)",
+				"\tat com.example.Main.main(Main.java:5)",
+				"Caused by: java.io.IOException: oops",
+				"\t... 2 more",
+				"INFO next",
+			},
+			[]string{
+				"INFO starting",
+				"Exception in thread \"main\" java.lang.IllegalStateException: oh noes\n" +
+					"\tat com.example.Main.run(Main.java:12)\n" +
+					"\tat com.example.Main.main(Main.java:5)\n" +
+					"Caused by: java.io.IOException: oops\n" +
+					"\t... 2 more",
+				"INFO next",
+			}},
+		{"python", &MultilinePython,
+			[]string{
+				"Traceback (most recent call last):",
+				`  File "x.py", line 1, in <module>`,
+				"    main()",
+				"ValueError: oh noes",
+				"INFO next",
+			},
+			[]string{
+				"Traceback (most recent call last):\n" +
+					"  File \"x.py\", line 1, in <module>\n" +
+					"    main()\n" +
+					"ValueError: oh noes",
+				"INFO next",
+			}},
+		{"go", &MultilineGo,
+			[]string{
+				"panic: oh noes",
+				"",
+				"goroutine 1 [running]:",
+				"main.(*T).run(...)",
+				"\t/home/martin/x.go:5",
+				"main.main()",
+				"\t/home/martin/x.go:10 +0x1d",
+				"exit status 2",
+				"next",
+			},
+			[]string{
+				"panic: oh noes\n\n" +
+					"goroutine 1 [running]:\n" +
+					"main.(*T).run(...)\n" +
+					"\t/home/martin/x.go:5\n" +
+					"main.main()\n" +
+					"\t/home/martin/x.go:10 +0x1d\n" +
+					"exit status 2",
+				"next",
+			}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, tmp, lines := startWith(context.Background(), t, func(f *Follower) {
+				m := *tt.m
+				m.Timeout = 50 * time.Millisecond
+				f.Multiline = &m
+			})
+			write(t, tmp, tt.lines...)
+			time.Sleep(100 * time.Millisecond)
+
+			f.Stop()
+			got := <-lines
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\ngot:  %q\nwant: %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMultilinePreset(t *testing.T) {
+	for _, n := range []string{"java", "python", "go"} {
+		if m, ok := MultilinePreset(n); !ok || m.Continue == nil {
+			t.Errorf("%s: %v %v", n, m, ok)
+		}
+	}
+	if _, ok := MultilinePreset("nope"); ok {
+		t.Error("ok for nope")
+	}
+}
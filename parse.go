@@ -0,0 +1,63 @@
+package follow
+
+import "time"
+
+// Parser parses lines in to structured records.
+//
+// This package includes parsers for Logfmt, Docker, CRI, and Timestamp; for
+// example to follow a Docker container log:
+//
+//	f.Parser = follow.Docker{}
+type Parser interface {
+	// Parse a single line, without the trailing newline.
+	//
+	// If this returns an error the line is sent with Data.Err set to the
+	// error.
+	Parse(line []byte) (Record, error)
+}
+
+// Record is the structured data parsed from a line.
+type Record struct {
+	Fields    map[string]string // Key/value fields.
+	Stream    string            // Output stream, e.g. "stdout" or "stderr".
+	Timestamp time.Time         // Time the line was logged.
+
+	// Message to use for Data.Bytes instead of the full line, for formats
+	// that add metadata to every line.
+	//
+	// Default is nil, which means the full line is used.
+	Message []byte
+
+	// Record continues on the next line; the Message of the next line is
+	// appended to this one, and the other fields of the first line are used.
+	Partial bool
+}
+
+// Parse a line with f.Parser, joining partial records.
+//
+// Note: callers should lock!
+func (f *Follower) parse(line Data) (Data, bool) {
+	rec, err := f.Parser.Parse(line.Bytes)
+	if err != nil {
+		f.record = nil
+		line.Err = err
+		return line, true
+	}
+
+	d := line
+	d.Record = rec
+	if rec.Message != nil {
+		d.Bytes = rec.Message
+	}
+	if f.record != nil {
+		f.record.Bytes = append(f.record.Bytes, d.Bytes...)
+		d = *f.record
+	}
+
+	if rec.Partial && f.keep(d) {
+		return Data{}, false
+	}
+	f.record = nil
+	d.Message, d.Partial = nil, false
+	return d, true
+}
@@ -0,0 +1,61 @@
+package follow
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Test parser: "+" at the end means the line continues, lines starting with
+// "!" are errors, and everything else is parsed as logfmt.
+type testParser struct{}
+
+func (testParser) Parse(l []byte) (Record, error) {
+	if bytes.HasPrefix(l, []byte("!")) {
+		return Record{}, errors.New("oh noes")
+	}
+	if bytes.HasSuffix(l, []byte("+")) {
+		return Record{Message: l[:len(l)-1], Partial: true, Stream: string(l)}, nil
+	}
+	return Record{Fields: parseLogfmt(l)}, nil
+}
+
+func TestParser(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "f")
+	touch(t, tmp)
+
+	f := New()
+	f.Parser = testParser{}
+	stop := f.Stop
+	go f.Start(context.Background(), tmp)
+	<-f.Ready
+
+	write(t, tmp, "a=1 b=2", "one+", "two+", "three", "!error", "c=3")
+
+	got := make([]Data, 0, 4)
+	for i := 0; i < 4; i++ {
+		d := <-f.Data
+		if d.Err != nil {
+			d.Err = errors.New(d.Err.Error())
+		}
+		got = append(got, d)
+	}
+	go stop()
+	if d := <-f.Data; d.Err != io.EOF {
+		t.Fatalf("not EOF: %#v", d)
+	}
+
+	want := []Data{
+		{Bytes: []byte("a=1 b=2"), Record: Record{Fields: map[string]string{"a": "1", "b": "2"}}},
+		{Bytes: []byte("onetwothree"), Record: Record{Stream: "one+"}},
+		{Bytes: []byte("!error"), Err: errors.New("oh noes")},
+		{Bytes: []byte("c=3"), Record: Record{Fields: map[string]string{"c": "3"}}},
+	}
+	if got := clean(got); !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
@@ -0,0 +1,79 @@
+package follow
+
+import (
+	"context"
+	"io"
+)
+
+// Pipe reads lines from another Follower's Data channel (or any other channel)
+// and sends them through this Follower's processing: Encoding, CSV, Parser,
+// Multiline, Alerts, etc.
+//
+// For example, to parse only lines with ERROR:
+//
+//	raw := follow.New()
+//	raw.Severity = &follow.Severity{Tokens: []string{"ERROR"}}
+//	raw.Go(ctx, "app.log")
+//
+//	parsed := follow.New()
+//	parsed.Parser = follow.Logfmt{}
+//	go parsed.Pipe(ctx, raw.Data)
+//
+// Data.Name, Offset, Line, and ReadAt are copied from the input, so they still
+// point to the line in the original file. This returns once io.EOF is read, the
+// channel is closed, or the context is cancelled. Errors are sent through
+// as-is.
+func (f *Follower) Pipe(ctx context.Context, in <-chan Data) error {
+	close(f.Ready)
+	f.stopOnCancel(ctx)
+	defer func() {
+		f.Stop()
+		f.sendFinal(Data{Err: io.EOF})
+	}()
+
+	for {
+		var (
+			d  Data
+			ok bool
+		)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-f.done:
+			return nil
+		case <-f.multiTimeout():
+			f.sendMultiline()
+			continue
+		case d, ok = <-in:
+		}
+		if !ok || d.Err == io.EOF {
+			break
+		}
+		f.pipe(d)
+	}
+	f.sendMultiline()
+	return nil
+}
+
+// Send a line from another Follower through this one.
+func (f *Follower) pipe(d Data) {
+	f.fpMu.Lock()
+	f.name = d.Name
+	if d.Err != nil {
+		f.fpMu.Unlock()
+		f.send(Data{Err: d.Err})
+		return
+	}
+	// Lines from a Batch follow each other, so only the position of the first
+	// needs to be set.
+	f.readAt, f.offset, f.lineno = d.ReadAt, d.Offset, d.Line-1
+	var lines []Data
+	for _, b := range d.batch() {
+		line := append(append(make([]byte, 0, len(b)+1), b...), '\n')
+		lines = append(lines, f.process(line)...)
+	}
+	f.fpMu.Unlock()
+
+	f.sendAll(lines)
+	f.sendEvents()
+}
@@ -0,0 +1,129 @@
+package follow
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPipe(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "f")
+	touch(t, tmp)
+
+	raw := New()
+	raw.Severity = &Severity{Tokens: []string{"ERROR"}}
+	err := raw.Go(context.Background(), tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed := New()
+	parsed.Parser = Logfmt{}
+	go parsed.Pipe(context.Background(), raw.Data)
+	<-parsed.Ready
+
+	write(t, tmp, "INFO msg=skip", "ERROR msg=one", "ERROR msg=two")
+	var got []string
+	for i := 0; i < 2; i++ {
+		d := <-parsed.Data
+		if d.Name != tmp {
+			t.Errorf("wrong name: %q", d.Name)
+		}
+		got = append(got, d.Fields["msg"])
+	}
+	raw.Stop()
+	if d := <-parsed.Data; d.Err != io.EOF {
+		t.Errorf("wrong error: %v", d.Err)
+	}
+
+	want := []string{"one", "two"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+// A pending multiline record is sent after Multiline.Timeout, without waiting
+// for the next line.
+func TestPipeMultilineTimeout(t *testing.T) {
+	in := make(chan Data)
+	f := New()
+	f.Multiline = &Multiline{Continue: MultilineJava.Continue, Timeout: 50 * time.Millisecond}
+	go f.Pipe(context.Background(), in)
+	<-f.Ready
+
+	in <- Data{Bytes: []byte("2024-05-01 error")}
+	in <- Data{Bytes: []byte("\tat Foo.bar")}
+	select {
+	case d := <-f.Data:
+		if want := "2024-05-01 error\n\tat Foo.bar"; string(d.Bytes) != want {
+			t.Errorf("\ngot:  %q\nwant: %q", d.Bytes, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("record not sent after Timeout")
+	}
+	f.Stop()
+}
+
+// Status and Stats can be called from any goroutine while piping.
+func TestPipeStatus(t *testing.T) {
+	in := make(chan Data)
+	f := New()
+	go f.Pipe(context.Background(), in)
+	<-f.Ready
+
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		for !isClosed(stop) {
+			_ = f.String()
+			_ = f.Stats()
+		}
+	}()
+	go func() {
+		for i := 0; i < 100; i++ {
+			in <- Data{Name: "f", Bytes: []byte("line")}
+		}
+		close(in)
+	}()
+	if got := len(drain(f.Data)); got != 100 {
+		t.Errorf("got %d lines", got)
+	}
+	close(stop)
+	<-done
+}
+
+// Offset, Line, and ReadAt are from the input, not counted by the Follower
+// that pipes it.
+func TestPipePosition(t *testing.T) {
+	in := make(chan Data, 3)
+	readAt := time.Date(2024, 5, 1, 14, 32, 0, 0, time.UTC)
+	in <- Data{Bytes: []byte("one"), Offset: 1000, Line: 50, ReadAt: readAt}
+	in <- Data{Batch: [][]byte{[]byte("two"), []byte("three")}, Offset: 2000, Line: 80, ReadAt: readAt}
+	in <- Data{Err: io.EOF}
+
+	f := New()
+	f.Data = make(chan Data, 10)
+	err := f.Pipe(context.Background(), in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for d := range f.Data {
+		if d.Err == io.EOF {
+			break
+		}
+		if !d.ReadAt.Equal(readAt) {
+			t.Errorf("wrong ReadAt for %q: %s", d, d.ReadAt)
+		}
+		got = append(got, fmt.Sprintf("%d:%d %s", d.Line, d.Offset, d))
+	}
+	want := []string{"50:1000 one", "80:2000 two", "81:2004 three"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+}
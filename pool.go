@@ -0,0 +1,56 @@
+package follow
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Read buffer that's shared by all the lines that were read from it, if
+// Follower.Pool is set.
+type chunk struct {
+	buf  []byte
+	refs int32
+}
+
+var chunks sync.Pool
+
+func getChunk(size int) *chunk {
+	c, _ := chunks.Get().(*chunk)
+	if c == nil || cap(c.buf) < size {
+		return &chunk{buf: make([]byte, size)}
+	}
+	c.buf = c.buf[:size]
+	return c
+}
+
+// Take a reference for every line in data, or put it back in the pool if there
+// are no lines.
+func (c *chunk) hold(data []Data) {
+	if c == nil {
+		return
+	}
+	for i := range data {
+		data[i].chunk = c
+	}
+	c.refs = int32(len(data))
+	if c.refs == 0 {
+		chunks.Put(c)
+	}
+}
+
+func (c *chunk) release() {
+	if c != nil && atomic.AddInt32(&c.refs, -1) == 0 {
+		chunks.Put(c)
+	}
+}
+
+// Release the memory for this line, if Follower.Pool is set. Bytes, Batch, and
+// anything that refers to them (such as Record) can't be used after this.
+//
+// This should be called once for every Data received; it does nothing if Pool
+// isn't set. Lines that aren't released are garbage collected as usual, but
+// the memory isn't reused.
+func (d *Data) Release() {
+	d.chunk.release()
+	d.chunk = nil
+}
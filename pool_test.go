@@ -0,0 +1,83 @@
+package follow
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPool(t *testing.T) {
+	f, tmp, data := startData(context.Background(), t, func(f *Follower) {
+		f.Pool = true
+		f.ReadSize = 8
+	})
+	appendString(t, tmp, "one\ntwo\nthree\nfour\n")
+	appendString(t, tmp, "five\nsix\n")
+	f.Stop()
+
+	// Lines that aren't released stay valid.
+	var got []string
+	for _, d := range <-data {
+		got = append(got, d.String())
+	}
+	if want := []string{"one", "two", "three", "four", "five", "six"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestChunk(t *testing.T) {
+	c := getChunk(4)
+	data := make([]Data, 2)
+	c.hold(data)
+
+	data[0].Release()
+	data[0].Release() // Does nothing the second time.
+	if c.refs != 1 {
+		t.Fatalf("refs = %d; want 1", c.refs)
+	}
+	data[1].Release()
+	if c.refs != 0 {
+		t.Fatalf("refs = %d; want 0", c.refs)
+	}
+
+	var d Data
+	d.Release() // Not pooled.
+}
+
+func BenchmarkPool(b *testing.B) {
+	lines := strings.Repeat(strings.Repeat("x", 100)+"\n", 1000)
+	for _, pool := range []bool{false, true} {
+		b.Run(fmt.Sprintf("%t", pool), func(b *testing.B) {
+			tmp := filepath.Join(b.TempDir(), "f")
+			fp, err := os.Create(tmp)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer fp.Close()
+
+			f := New()
+			f.Pool = pool
+			f.Batch = 100
+			err = f.Go(context.Background(), tmp)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer f.stopAndDrain()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				fp.WriteString(lines)
+				for i := 0; i < 1000; {
+					d := <-f.Data
+					i += len(d.batch())
+					d.Release()
+				}
+			}
+		})
+	}
+}
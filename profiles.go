@@ -0,0 +1,49 @@
+package follow
+
+import "time"
+
+// Interactive returns a Follower for showing a file to a person, like tail -f.
+//
+// Lines are truncated at 4K and invalid UTF-8 is replaced, so a bad line can't
+// mess up the terminal, and it gives up quickly if the file goes away. If the
+// terminal can't keep up it drops the oldest lines rather than stalling.
+func Interactive() Follower {
+	f := New()
+	f.Data = make(chan Data, 1000)
+	f.Overflow = OverflowDropOldest
+	f.Retry = 2 * time.Second
+	f.MaxLineLen = 4 << 10
+	f.LongLines = LongTruncate
+	f.InvalidUTF8 = UTF8Replace
+	return f
+}
+
+// Shipper returns a Follower for sending a file to somewhere else, such as a
+// log aggregator.
+//
+// It retries forever, buffers 1,000 lines on the Data channel so a slow
+// receiver doesn't stall reading, and splits lines longer than 1M so nothing
+// is lost while memory stays bounded.
+func Shipper() Follower {
+	f := New()
+	f.Data = make(chan Data, 1000)
+	f.Retry = -1
+	f.MaxLineLen = 1 << 20
+	f.LongLines = LongSplit
+	return f
+}
+
+// Forensic returns a Follower for reading a file exactly as it was written.
+//
+// It reads from the start of the file, lines are never truncated or modified,
+// line numbers are reset on rotation so Data.Line matches the line in the file
+// on disk, and it retries forever.
+func Forensic() Follower {
+	f := New()
+	f.FromStart = true
+	f.Retry = -1
+	f.MaxLineLen = 0
+	f.InvalidUTF8 = UTF8Raw
+	f.ResetLines = true
+	return f
+}
@@ -0,0 +1,74 @@
+package follow
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProfiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile func() Follower
+		want    []string
+	}{
+		{"interactive", Interactive, []string{"ok", strings.Repeat("x", 4096), "bad�"}},
+		{"shipper", Shipper, []string{"ok", strings.Repeat("x", 4096), "x", "bad\xff"}},
+		{"forensic", Forensic, []string{"ok", strings.Repeat("x", 4097), "bad\xff"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, tmp, lines := startWith(context.Background(), t, func(f *Follower) {
+				*f = tt.profile()
+				if tt.name == "shipper" {
+					f.MaxLineLen = 4096
+				}
+			})
+			write(t, tmp, "ok", strings.Repeat("x", 4097), "bad\xff")
+
+			f.Stop()
+			got := <-lines
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\ngot:  %q\nwant: %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Forensic reads lines that were in the file before it started, so Data.Line
+// matches the file on disk.
+func TestForensicLine(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "f")
+	touch(t, tmp)
+	write(t, tmp, "one", "two")
+
+	f := Forensic()
+	go func() {
+		if err := f.Start(context.Background(), tmp); err != nil {
+			t.Error(err)
+		}
+	}()
+	<-f.Ready
+	write(t, tmp, "three")
+
+	var got []int64
+	for len(got) < 3 {
+		select {
+		case d := <-f.Data:
+			if d.Err != nil {
+				t.Fatal(d.Err)
+			}
+			got = append(got, d.Line)
+		case <-time.After(time.Second):
+			t.Fatalf("timeout; got %v", got)
+		}
+	}
+	f.stopAndDrain()
+	if want := []int64{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,72 @@
+package follow
+
+import "time"
+
+// Rate limits how fast lines are sent on Data, for example to a remote API or
+// a terminal. Lines over the limit are delayed, not dropped; reading the file
+// waits as well. Errors aren't limited, and lines that are still waiting when
+// Stop is called aren't sent.
+//
+// For example, to send at most 100 lines and 64K per second:
+//
+//	f.Rate = &follow.Rate{Lines: 100, Bytes: 64 << 10}
+//
+// The same Rate can be used for several followers; the limit applies to every
+// Follower separately.
+type Rate struct {
+	// Maximum number of lines or bytes per second; 0 means no limit. Bytes
+	// doesn't count newlines.
+	Lines float64
+	Bytes float64
+
+	// Allow bursts of up to this period's worth of lines or bytes after being
+	// idle.
+	//
+	// Default is 0, which means one second.
+	Burst time.Duration
+}
+
+// Tokens for a Rate; this is kept in the Follower.
+type rateState struct {
+	lines, bytes float64 // Available tokens; negative if we're behind.
+	last         time.Time
+}
+
+// Take tokens for d and return how long to wait before sending it.
+func (r *Rate) delay(s *rateState, d Data, now time.Time) time.Duration {
+	var n, size int
+	for _, l := range d.batch() {
+		n, size = n+1, size+len(l)
+	}
+
+	burst := r.Burst
+	if burst <= 0 {
+		burst = time.Second
+	}
+	elapsed := now.Sub(s.last).Seconds()
+	if s.last.IsZero() {
+		elapsed = burst.Seconds()
+	}
+	s.last = now
+
+	take := func(tokens *float64, rate float64, n int) time.Duration {
+		if rate <= 0 {
+			return 0
+		}
+		*tokens += elapsed * rate
+		if limit := burst.Seconds() * rate; *tokens > limit {
+			*tokens = limit
+		}
+		*tokens -= float64(n)
+		if *tokens >= 0 {
+			return 0
+		}
+		return time.Duration(-*tokens / rate * float64(time.Second))
+	}
+
+	wait := take(&s.lines, r.Lines, n)
+	if w := take(&s.bytes, r.Bytes, size); w > wait {
+		wait = w
+	}
+	return wait
+}
@@ -0,0 +1,58 @@
+package follow
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRate(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	line := func(n int) Data { return Data{Bytes: []byte(strings.Repeat("x", n))} }
+
+	tests := []struct {
+		r    Rate
+		data []Data
+		at   []time.Duration
+		want []time.Duration
+	}{
+		{Rate{Lines: 2},
+			[]Data{line(1), line(1), line(1), line(1)},
+			[]time.Duration{0, 0, 0, time.Second},
+			[]time.Duration{0, 0, 500 * time.Millisecond, 0}},
+		{Rate{Bytes: 100},
+			[]Data{line(150), line(10)},
+			[]time.Duration{0, 0},
+			[]time.Duration{500 * time.Millisecond, 600 * time.Millisecond}},
+		{Rate{Lines: 10, Burst: 100 * time.Millisecond},
+			[]Data{{Batch: [][]byte{nil, nil, nil}}},
+			[]time.Duration{0},
+			[]time.Duration{200 * time.Millisecond}},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			var s rateState
+			for i, d := range tt.data {
+				got := tt.r.delay(&s, d, now.Add(tt.at[i]))
+				if got != tt.want[i] {
+					t.Errorf("%d: got %s; want %s", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRateFollow(t *testing.T) {
+	f, tmp, lines := startWith(context.Background(), t, func(f *Follower) {
+		f.Rate = &Rate{Lines: 100, Burst: 10 * time.Millisecond}
+	})
+
+	write(t, tmp, "1", "2", "3", "4", "5")
+	time.Sleep(100 * time.Millisecond)
+	f.Stop()
+	if got := <-lines; len(got) != 5 {
+		t.Errorf("wrong lines: %q", got)
+	}
+}
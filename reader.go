@@ -0,0 +1,117 @@
+package follow
+
+import (
+	"context"
+	"io"
+)
+
+type reader struct {
+	f   *Follower
+	buf []byte
+	eof bool
+}
+
+// Reader returns an io.ReadCloser that reads the lines sent on Data, with a
+// newline after every line. Read blocks until a new line is written.
+//
+// Read returns errors sent on Data, but can be called again after that to keep
+// following the file. io.EOF is returned after Stop. Close calls Stop.
+func (f *Follower) Reader() io.ReadCloser {
+	return &reader{f: f}
+}
+
+// NewReader follows the file and returns a Reader for it; see
+// Follower.Reader.
+func NewReader(path string) (io.ReadCloser, error) {
+	f := New()
+	err := f.Go(context.Background(), path)
+	if err != nil {
+		return nil, err
+	}
+	return f.Reader(), nil
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 {
+		if r.eof {
+			return 0, io.EOF
+		}
+		d := <-r.f.Data
+		if d.Err == io.EOF {
+			r.eof = true
+			return 0, io.EOF
+		}
+		if d.Err != nil {
+			return 0, d.Err
+		}
+		for _, l := range d.batch() {
+			r.buf = append(append(r.buf, l...), '\n')
+		}
+		d.Release()
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *reader) Close() error {
+	r.f.Stop()
+	for !r.eof {
+		if d := <-r.f.Data; d.Err == io.EOF {
+			r.eof = true
+		}
+	}
+	r.buf = nil
+	return nil
+}
+
+// Copy writes all lines to w, with a newline after every line, until the
+// context is cancelled, Stop is called, or there's an error.
+//
+// If w has a Flush() or Flush() error method (such as bufio.Writer or
+// http.ResponseWriter) then it's called after every line, or every batch if
+// Batch is set; wrap w in a type without Flush to prevent this.
+//
+// This is called Copy rather than WriteTo since it doesn't implement
+// io.WriterTo.
+func (f *Follower) Copy(ctx context.Context, w io.Writer) (int64, error) {
+	var (
+		n    int64
+		line []byte
+	)
+	for {
+		var d Data
+		select {
+		case <-ctx.Done():
+			return n, ctx.Err()
+		case d = <-f.Data:
+		}
+		if d.Err == io.EOF {
+			return n, nil
+		}
+		if d.Err != nil {
+			return n, d.Err
+		}
+
+		line = line[:0]
+		for _, l := range d.batch() {
+			line = append(append(line, l...), '\n')
+		}
+		d.Release()
+		wrote, err := w.Write(line)
+		n += int64(wrote)
+		if err != nil {
+			return n, err
+		}
+
+		switch ww := w.(type) {
+		case interface{ Flush() error }:
+			if err := ww.Flush(); err != nil {
+				return n, err
+			}
+		case interface{ Flush() }:
+			ww.Flush()
+		}
+	}
+}
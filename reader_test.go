@@ -0,0 +1,80 @@
+package follow
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "f")
+	touch(t, tmp)
+
+	r, err := NewReader(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write(t, tmp, "one", strings.Repeat("x", 10_000), "three")
+
+	var got []string
+	scan := bufio.NewScanner(r)
+	for i := 0; i < 3 && scan.Scan(); i++ {
+		got = append(got, scan.Text())
+	}
+	want := []string{"one", strings.Repeat("x", 10_000), "three"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong lines: %d", len(got))
+	}
+
+	err = r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := r.Read(make([]byte, 10)); n != 0 || err != io.EOF {
+		t.Errorf("read after close: %d, %v", n, err)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "f")
+	touch(t, tmp)
+
+	f := New()
+	err := f.Go(context.Background(), tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		buf         = new(lockedBuffer)
+		bw          = bufio.NewWriter(buf)
+		done        = make(chan int64)
+		ctx, cancel = context.WithCancel(context.Background())
+	)
+	go func() {
+		n, err := f.Copy(ctx, bw)
+		if err != context.Canceled {
+			t.Error(err)
+		}
+		done <- n
+	}()
+
+	write(t, tmp, "one", "two")
+	if s := buf.String(); s != "one\ntwo\n" {
+		t.Errorf("not flushed: %q", s)
+	}
+
+	cancel()
+	if n := <-done; n != 8 {
+		t.Errorf("n = %d", n)
+	}
+	f.Stop()
+	if d := <-f.Data; d.Err != io.EOF {
+		t.Errorf("wrong error: %v", d.Err)
+	}
+}
@@ -0,0 +1,75 @@
+package follow
+
+import "regexp"
+
+// Redact returns a Transform that replaces all matches of re with repl, which
+// can refer to submatches with $1 etc. as in regexp.Regexp.Expand.
+//
+// For example, to hide passwords in URLs:
+//
+//	f.Transforms = append(f.Transforms,
+//		follow.Redact(regexp.MustCompile(`(://[^:/]+:)[^@]+@`), "${1}[REDACTED]@"))
+//
+// Only Data.Bytes is changed: Data.Columns and Data.Record are parsed before
+// Transforms run, so use a Parser that drops the field if you need it removed
+// from there too.
+func Redact(re *regexp.Regexp, repl string) Transform {
+	r := []byte(repl)
+	return func(d Data) (Data, bool) {
+		if re.Match(d.Bytes) {
+			d.Bytes = re.ReplaceAll(d.Bytes, r)
+		}
+		return d, true
+	}
+}
+
+var (
+	// RedactBearer replaces bearer tokens, such as in a logged Authorization
+	// header:
+	//
+	//	Authorization: Bearer [REDACTED]
+	RedactBearer = Redact(regexp.MustCompile(`(?i)(\bbearer\s+)[a-z0-9\-._~+/]+=*`), "${1}[REDACTED]")
+
+	// RedactCard replaces credit card numbers with [REDACTED], with or without
+	// spaces or dashes between the digits. Only numbers that pass the Luhn
+	// checksum are replaced, so most other long numbers are left alone.
+	RedactCard Transform = redactCard
+)
+
+var reCard = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+
+func redactCard(d Data) (Data, bool) {
+	if !reCard.Match(d.Bytes) {
+		return d, true
+	}
+	d.Bytes = reCard.ReplaceAllFunc(d.Bytes, func(m []byte) []byte {
+		if !luhn(m) {
+			return m
+		}
+		return []byte("[REDACTED]")
+	})
+	return d, true
+}
+
+// Report if the digits in b pass the Luhn checksum; other characters are
+// ignored.
+func luhn(b []byte) bool {
+	var (
+		sum    int
+		double bool
+	)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < '0' || b[i] > '9' {
+			continue
+		}
+		n := int(b[i] - '0')
+		if double {
+			n *= 2
+			if n > 9 {
+				n -= 9
+			}
+		}
+		sum, double = sum+n, !double
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,40 @@
+package follow
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		t          Transform
+		line, want string
+	}{
+		{Redact(regexp.MustCompile(`(://[^:/]+:)[^@]+@`), "${1}[REDACTED]@"),
+			"connecting to postgres://user:hunter2@db/x",
+			"connecting to postgres://user:[REDACTED]@db/x"},
+		{Redact(regexp.MustCompile(`secret`), "x"), "nothing here", "nothing here"},
+
+		{RedactBearer, "Authorization: Bearer abc.DEF-123_x/y+z==", "Authorization: Bearer [REDACTED]"},
+		{RedactBearer, "authorization: bearer abc def", "authorization: bearer [REDACTED] def"},
+		{RedactBearer, "no token", "no token"},
+
+		{RedactCard, "paid with 4111 1111 1111 1111 ok", "paid with [REDACTED] ok"},
+		{RedactCard, "paid with 4111-1111-1111-1111", "paid with [REDACTED]"},
+		{RedactCard, "paid with 4111111111111111", "paid with [REDACTED]"},
+		{RedactCard, "ts=1700000000001 id=4111111111111112", "ts=1700000000001 id=4111111111111112"},
+		{RedactCard, "short 4111", "short 4111"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			d, ok := tt.t(Data{Bytes: []byte(tt.line)})
+			if !ok {
+				t.Fatal("dropped")
+			}
+			if got := d.String(); got != tt.want {
+				t.Errorf("\ngot:  %q\nwant: %q", got, tt.want)
+			}
+		})
+	}
+}
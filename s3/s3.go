@@ -0,0 +1,449 @@
+// Package s3 archives followed lines as objects in S3-compatible storage, such
+// as AWS S3 or MinIO.
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"zgo.at/follow"
+)
+
+// DefaultPrefix is the default key prefix, which starts new objects every day,
+// e.g. "logs/2024/05/01".
+var DefaultPrefix = template.Must(template.New("").Funcs(Funcs).Parse(
+	`logs/{{(time .).Format "2006/01/02"}}`))
+
+// Funcs are the functions available in prefix templates.
+//
+//	time   Timestamp for the line; see Timestamp.
+var Funcs = template.FuncMap{
+	"time": Timestamp,
+}
+
+// Timestamp gets the time for a line: Record.Timestamp if Follower.Parser set
+// it, or the time it was read otherwise. It's always in UTC.
+func Timestamp(d follow.Data) time.Time {
+	if !d.Timestamp.IsZero() {
+		return d.Timestamp.UTC()
+	}
+	return d.ReadAt.UTC()
+}
+
+// S3 uploads lines to a bucket.
+//
+// Lines are collected in an object for every key prefix, which is uploaded
+// once it reaches MaxSize or MaxAge. Objects larger than PartSize are uploaded
+// in parts while they're still being written, so at most about PartSize is kept
+// in memory for every object.
+//
+// Fields that are zero use their default, except Retries, where 0 means
+// requests are never retried. New sets all the defaults.
+type S3 struct {
+	// Endpoint and bucket; objects are uploaded to Endpoint/Bucket/key, e.g.
+	// "https://s3.eu-west-1.amazonaws.com" or "http://localhost:9000".
+	Endpoint string
+	Bucket   string
+
+	// Region and credentials to sign requests with AWS Signature Version 4.
+	//
+	// Default is "us-east-1" for the Region, and no AccessKey, which means
+	// requests aren't signed.
+	Region       string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+
+	// Template for the key prefix; this gets a follow.Data, so labels from
+	// Record.Fields can be used with e.g. {{index .Fields "service"}}. Use
+	// template.New("").Funcs(s3.Funcs) to get the "time" function.
+	//
+	// Lines with the same prefix are written to the same object; the key is
+	// the prefix followed by the time the object was started, e.g.
+	// "logs/2024/05/01/20240501T143200.123456789Z.log.gz".
+	//
+	// Default is DefaultPrefix.
+	Prefix *template.Template
+
+	// Don't compress objects; the key ends with ".log" instead of ".log.gz".
+	//
+	// Default is false, which means objects are compressed with gzip.
+	NoGzip bool
+
+	// Upload an object once this many bytes were written to it (before
+	// compression), or once it was started this long ago.
+	//
+	// Default is 100M and 5 minutes.
+	MaxSize int64
+	MaxAge  time.Duration
+
+	// Upload objects larger than this (after compression) in parts of this
+	// size with a multipart upload. AWS requires at least 5M.
+	//
+	// Default is 8M.
+	PartSize int64
+
+	// Number of times to retry a request after a 429 or 5xx response or an
+	// error, waiting RetryWait between the first attempts and doubling it
+	// every time.
+	//
+	// Default is 5 retries, starting at 1 second.
+	Retries   int
+	RetryWait time.Duration
+
+	// Report objects that were dropped because an upload failed.
+	//
+	// Default is nil, which means errors are logged with the standard log
+	// package.
+	OnError func(error)
+
+	// Client to use; default is http.DefaultClient.
+	Client *http.Client
+}
+
+// New creates a new S3 sink with the default settings.
+func New(endpoint, bucket string) *S3 {
+	return &S3{
+		Endpoint:  endpoint,
+		Bucket:    bucket,
+		Region:    "us-east-1",
+		Prefix:    DefaultPrefix,
+		MaxSize:   100 << 20,
+		MaxAge:    5 * time.Minute,
+		PartSize:  8 << 20,
+		Retries:   5,
+		RetryWait: time.Second,
+		Client:    http.DefaultClient,
+	}
+}
+
+// Copy of s with the defaults for fields that are zero, so an S3 that wasn't
+// created with New works too.
+func (s *S3) withDefaults() *S3 {
+	c := *s
+	if c.Region == "" {
+		c.Region = "us-east-1"
+	}
+	if c.Prefix == nil {
+		c.Prefix = DefaultPrefix
+	}
+	if c.MaxSize <= 0 {
+		c.MaxSize = 100 << 20
+	}
+	if c.MaxAge <= 0 {
+		c.MaxAge = 5 * time.Minute
+	}
+	if c.PartSize <= 0 {
+		c.PartSize = 8 << 20
+	}
+	if c.RetryWait <= 0 {
+		c.RetryWait = time.Second
+	}
+	if c.Client == nil {
+		c.Client = http.DefaultClient
+	}
+	return &c
+}
+
+// An object that's being written.
+type object struct {
+	key     string
+	started time.Time
+	size    int64        // Bytes written, before compression.
+	buf     bytes.Buffer // Data not uploaded yet.
+	gz      *gzip.Writer // nil if NoGzip is set.
+	upload  string       // UploadId, once the first part was uploaded.
+	parts   []string     // ETags of the uploaded parts.
+}
+
+func (s *S3) newObject(prefix string, now time.Time) *object {
+	o := &object{started: now}
+	o.key = strings.TrimSuffix(prefix, "/") + "/" + now.UTC().Format("20060102T150405.000000000Z") + ".log"
+	if !s.NoGzip {
+		o.key += ".gz"
+		o.gz = gzip.NewWriter(&o.buf)
+	}
+	return o
+}
+
+func (o *object) write(line []byte) {
+	o.size += int64(len(line)) + 1
+	w := io.Writer(&o.buf)
+	if o.gz != nil {
+		w = o.gz
+	}
+	w.Write(line)
+	w.Write([]byte{'\n'})
+}
+
+func (s *S3) error(err error) {
+	if s.OnError != nil {
+		s.OnError(err)
+	} else {
+		log.Print("s3: ", err)
+	}
+}
+
+// Run uploads all lines from data, until io.EOF is received, the channel is
+// closed, or the context is cancelled.
+//
+// Data with an error other than io.EOF is skipped. Errors for objects that are
+// dropped because an upload failed are sent to OnError. Once data is done, this
+// uploads all objects that are still open.
+//
+// This returns ctx.Err() if the context is cancelled, in which case objects
+// that weren't uploaded yet are lost, or an error if the Prefix template fails.
+func (s *S3) Run(ctx context.Context, data <-chan follow.Data) error {
+	s = s.withDefaults()
+	tick := s.MaxAge
+	if tick > time.Second {
+		tick = time.Second
+	}
+	var (
+		objects = make(map[string]*object)
+		t       = time.NewTicker(tick)
+		prefix  = new(strings.Builder)
+	)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-t.C:
+			for p, o := range objects {
+				if now.Sub(o.started) >= s.MaxAge {
+					s.finish(ctx, o)
+					delete(objects, p)
+				}
+			}
+		case d, ok := <-data:
+			if !ok || d.Err == io.EOF {
+				keys := make([]string, 0, len(objects))
+				for p := range objects {
+					keys = append(keys, p)
+				}
+				sort.Strings(keys)
+				for _, p := range keys {
+					s.finish(ctx, objects[p])
+				}
+				return ctx.Err()
+			}
+			if d.Err != nil {
+				continue
+			}
+
+			prefix.Reset()
+			err := s.Prefix.Execute(prefix, d)
+			if err != nil {
+				return fmt.Errorf("s3.Run: %w", err)
+			}
+			p := prefix.String()
+			o := objects[p]
+			if o == nil {
+				o = s.newObject(p, time.Now())
+				objects[p] = o
+			}
+
+			if d.Batch != nil {
+				for _, l := range d.Batch {
+					o.write(l)
+				}
+			} else {
+				o.write(d.Bytes)
+			}
+
+			if o.size >= s.MaxSize {
+				s.finish(ctx, o)
+				delete(objects, p)
+			} else if int64(o.buf.Len()) >= s.PartSize {
+				err := s.uploadPart(ctx, o)
+				if err != nil {
+					s.drop(ctx, o, err)
+					delete(objects, p)
+				}
+			}
+		}
+	}
+}
+
+// Upload the rest of the object, completing the multipart upload if one was
+// started.
+func (s *S3) finish(ctx context.Context, o *object) {
+	if o.gz != nil {
+		o.gz.Close()
+	}
+	if o.upload == "" {
+		_, _, err := s.do(ctx, "PUT", o.key, nil, s.contentType(), o.buf.Bytes())
+		if err != nil {
+			s.drop(ctx, o, err)
+		}
+		return
+	}
+
+	if o.buf.Len() > 0 {
+		err := s.uploadPart(ctx, o)
+		if err != nil {
+			s.drop(ctx, o, err)
+			return
+		}
+	}
+
+	body := new(bytes.Buffer)
+	body.WriteString("<CompleteMultipartUpload>")
+	for i, etag := range o.parts {
+		fmt.Fprintf(body, "<Part><PartNumber>%d</PartNumber><ETag>", i+1)
+		xml.EscapeText(body, []byte(etag))
+		body.WriteString("</ETag></Part>")
+	}
+	body.WriteString("</CompleteMultipartUpload>")
+
+	_, resp, err := s.do(ctx, "POST", o.key, url.Values{"uploadId": {o.upload}}, nil, body.Bytes())
+	if err == nil {
+		// This can fail after the 200 OK is sent, in which case the body is an
+		// error rather than the result.
+		var e s3Error
+		if xml.Unmarshal(resp, &e) == nil && e.XMLName.Local == "Error" {
+			err = fmt.Errorf("POST %s: %s", o.key, e)
+		}
+	}
+	if err != nil {
+		s.drop(ctx, o, err)
+	}
+}
+
+// Upload everything in the buffer as the next part, starting the multipart
+// upload if this is the first part.
+func (s *S3) uploadPart(ctx context.Context, o *object) error {
+	if o.upload == "" {
+		_, resp, err := s.do(ctx, "POST", o.key, url.Values{"uploads": {""}}, s.contentType(), nil)
+		if err != nil {
+			return err
+		}
+		var r struct {
+			UploadID string `xml:"UploadId"`
+		}
+		err = xml.Unmarshal(resp, &r)
+		if err != nil || r.UploadID == "" {
+			return fmt.Errorf("POST %s: no UploadId in response: %v", o.key, err)
+		}
+		o.upload = r.UploadID
+	}
+
+	q := url.Values{"partNumber": {strconv.Itoa(len(o.parts) + 1)}, "uploadId": {o.upload}}
+	h, _, err := s.do(ctx, "PUT", o.key, q, nil, o.buf.Bytes())
+	if err != nil {
+		return err
+	}
+	o.parts = append(o.parts, h.Get("ETag"))
+	o.buf.Reset()
+	return nil
+}
+
+// Report that the object was dropped, and abort the multipart upload if there
+// is one. Errors from aborting are ignored: a bucket lifecycle rule should
+// remove incomplete uploads anyway.
+func (s *S3) drop(ctx context.Context, o *object, err error) {
+	s.error(fmt.Errorf("dropped %s: %w", o.key, err))
+	if o.upload != "" {
+		s.do(ctx, "DELETE", o.key, url.Values{"uploadId": {o.upload}}, nil, nil)
+	}
+}
+
+func (s *S3) contentType() http.Header {
+	if s.NoGzip {
+		return http.Header{"Content-Type": {"text/plain; charset=utf-8"}}
+	}
+	return http.Header{"Content-Type": {"application/gzip"}}
+}
+
+// An error response.
+type s3Error struct {
+	XMLName xml.Name
+	Code    string
+	Message string
+}
+
+func (e s3Error) String() string {
+	if e.Message == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Message
+}
+
+// Send a request for the key, retrying it after an error or a 429 or 5xx
+// response. This returns the headers and body of the response.
+func (s *S3) do(ctx context.Context, method, key string, query url.Values, h http.Header, body []byte) (http.Header, []byte, error) {
+	wait := s.RetryWait
+	for i := 0; ; i++ {
+		respH, resp, retry, err := s.request(ctx, method, key, query, h, body)
+		if err == nil {
+			return respH, resp, nil
+		}
+		if !retry || i >= s.Retries {
+			return nil, nil, err
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, nil, ctx.Err()
+		case <-t.C:
+		}
+		wait *= 2
+	}
+}
+
+func (s *S3) request(ctx context.Context, method, key string, query url.Values, h http.Header, body []byte) (http.Header, []byte, bool, error) {
+	u, err := url.Parse(s.Endpoint)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.Bucket + "/" + key
+	u.RawPath = escape(u.Path, true)
+	u.RawQuery = query.Encode()
+
+	r, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, false, err
+	}
+	for k, v := range h {
+		r.Header[k] = v
+	}
+	if s.AccessKey != "" {
+		s.sign(r, body, time.Now())
+	}
+
+	resp, err := s.Client.Do(r)
+	if err != nil {
+		return nil, nil, ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, ctx.Err() == nil, fmt.Errorf("%s %s: reading response: %w", method, key, err)
+	}
+
+	if resp.StatusCode >= 300 {
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		var e s3Error
+		if xml.Unmarshal(b, &e) == nil && e.Code != "" {
+			return nil, nil, retry, fmt.Errorf("%s %s: %s: %s", method, key, resp.Status, e)
+		}
+		return nil, nil, retry, fmt.Errorf("%s %s: %s", method, key, resp.Status)
+	}
+	return resp.Header, b, false, nil
+}
@@ -0,0 +1,235 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"text/template"
+	"time"
+
+	"zgo.at/follow"
+)
+
+// Fake S3 server that stores objects in memory.
+type fakeS3 struct {
+	t        *testing.T
+	mu       sync.Mutex
+	objects  map[string][]byte
+	parts    map[string]map[int][]byte // Parts by UploadId.
+	requests []string
+	fail     int // Respond with 503 to this many requests.
+}
+
+func newFake(t *testing.T) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{t: t, objects: make(map[string][]byte), parts: make(map[string]map[int][]byte)}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	q := r.URL.Query()
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+	f.requests = append(f.requests, r.Method+" "+r.URL.RawQuery)
+
+	if a := r.Header.Get("Authorization"); !strings.HasPrefix(a, "AWS4-HMAC-SHA256 Credential=AK/") {
+		f.t.Errorf("wrong Authorization: %q", a)
+	}
+	if sum := sha256.Sum256(body); r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+		f.t.Errorf("wrong X-Amz-Content-Sha256")
+	}
+	if f.fail > 0 {
+		f.fail--
+		w.WriteHeader(503)
+		fmt.Fprint(w, `<Error><Code>SlowDown</Code></Error>`)
+		return
+	}
+
+	switch {
+	case r.Method == "PUT" && q.Get("uploadId") != "":
+		n, _ := strconv.Atoi(q.Get("partNumber"))
+		f.parts[q.Get("uploadId")][n] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"etag%d"`, n))
+	case r.Method == "PUT":
+		f.objects[key] = body
+	case r.Method == "POST" && q.Has("uploads"):
+		id := fmt.Sprintf("upload%d", len(f.parts)+1)
+		f.parts[id] = make(map[int][]byte)
+		fmt.Fprintf(w, `<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>`, id)
+	case r.Method == "POST":
+		var c struct {
+			Part []struct {
+				PartNumber int
+				ETag       string
+			}
+		}
+		err := xml.Unmarshal(body, &c)
+		if err != nil {
+			f.t.Error(err)
+		}
+		var obj []byte
+		for i, p := range c.Part {
+			if p.PartNumber != i+1 || p.ETag != fmt.Sprintf(`"etag%d"`, i+1) {
+				f.t.Errorf("wrong part: %v", p)
+			}
+			obj = append(obj, f.parts[q.Get("uploadId")][p.PartNumber]...)
+		}
+		f.objects[key] = obj
+		fmt.Fprint(w, `<CompleteMultipartUploadResult></CompleteMultipartUploadResult>`)
+	default:
+		f.t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+	}
+}
+
+// Get the objects as "key: content", with the time in the key replaced by "T".
+func (f *fakeS3) list(t *testing.T) []string {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	re := regexp.MustCompile(`/\d{8}T\d{6}\.\d{9}Z\.`)
+	var list []string
+	for k, v := range f.objects {
+		if strings.HasSuffix(k, ".gz") {
+			gz, err := gzip.NewReader(bytes.NewReader(v))
+			if err != nil {
+				t.Fatal(err)
+			}
+			v, err = io.ReadAll(gz)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		list = append(list, fmt.Sprintf("%s: %q", re.ReplaceAllString(k, "/T."), v))
+	}
+	sort.Strings(list)
+	return list
+}
+
+func run(t *testing.T, s *S3, lines ...follow.Data) {
+	t.Helper()
+	data := make(chan follow.Data, len(lines)+1)
+	for _, d := range lines {
+		data <- d
+	}
+	data <- follow.Data{Err: io.EOF}
+	err := s.Run(context.Background(), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func line(app, l string) follow.Data {
+	return follow.Data{Bytes: []byte(l), Record: follow.Record{
+		Timestamp: time.Date(2024, 5, 1, 14, 32, 0, 0, time.UTC),
+		Fields:    map[string]string{"app": app},
+	}}
+}
+
+func TestRun(t *testing.T) {
+	f, srv := newFake(t)
+	f.fail = 1
+	s := New(srv.URL, "bucket")
+	s.AccessKey, s.SecretKey = "AK", "secret"
+	s.RetryWait = time.Millisecond
+	s.OnError = func(err error) { t.Error(err) }
+	s.Prefix = template.Must(template.New("").Funcs(Funcs).Parse(
+		`{{index .Fields "app"}}/{{(time .).Format "2006/01/02"}}`))
+
+	run(t, s, line("a", "one"), line("b", "two"), line("a", "three"),
+		follow.Data{Batch: [][]byte{[]byte("four"), []byte("five")}, Record: line("b", "").Record})
+
+	want := `[a/2024/05/01/T.log.gz: "one\nthree\n" b/2024/05/01/T.log.gz: "two\nfour\nfive\n"]`
+	if got := fmt.Sprint(f.list(t)); got != want {
+		t.Errorf("\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+func TestRunMultipart(t *testing.T) {
+	f, srv := newFake(t)
+	s := &S3{Endpoint: srv.URL, Bucket: "bucket", AccessKey: "AK", SecretKey: "secret", PartSize: 10, NoGzip: true}
+	s.OnError = func(err error) { t.Error(err) }
+
+	run(t, s, line("", "one two"), line("", "three"), line("", "four"))
+
+	want := `[logs/2024/05/01/T.log: "one two\nthree\nfour\n"]`
+	if got := fmt.Sprint(f.list(t)); got != want {
+		t.Errorf("\ngot:  %s\nwant: %s", got, want)
+	}
+	want = `[POST uploads= PUT partNumber=1&uploadId=upload1 PUT partNumber=2&uploadId=upload1 POST uploadId=upload1]`
+	if got := fmt.Sprint(f.requests); got != want {
+		t.Errorf("\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+func TestRunMax(t *testing.T) {
+	t.Run("size", func(t *testing.T) {
+		f, srv := newFake(t)
+		s := New(srv.URL, "bucket")
+		s.AccessKey, s.SecretKey = "AK", "secret"
+		s.MaxSize = 8
+
+		run(t, s, line("", "one"), line("", "two"), line("", "three"))
+		want := `[logs/2024/05/01/T.log.gz: "one\ntwo\n" logs/2024/05/01/T.log.gz: "three\n"]`
+		if got := fmt.Sprint(f.list(t)); got != want {
+			t.Errorf("\ngot:  %s\nwant: %s", got, want)
+		}
+	})
+
+	t.Run("age", func(t *testing.T) {
+		f, srv := newFake(t)
+		s := New(srv.URL, "bucket")
+		s.AccessKey, s.SecretKey = "AK", "secret"
+		s.MaxAge = 10 * time.Millisecond
+
+		data := make(chan follow.Data)
+		go s.Run(context.Background(), data)
+		data <- line("", "one")
+		time.Sleep(50 * time.Millisecond)
+
+		want := `[logs/2024/05/01/T.log.gz: "one\n"]`
+		if got := fmt.Sprint(f.list(t)); got != want {
+			t.Errorf("\ngot:  %s\nwant: %s", got, want)
+		}
+		close(data)
+	})
+}
+
+// Objects are dropped once they were retried Retries times.
+func TestRunDrop(t *testing.T) {
+	f, srv := newFake(t)
+	f.fail = 100
+	var errs []string
+	s := New(srv.URL, "bucket")
+	s.AccessKey, s.SecretKey = "AK", "secret"
+	s.Retries = 1
+	s.RetryWait = time.Millisecond
+	s.OnError = func(err error) { errs = append(errs, err.Error()) }
+
+	run(t, s, line("", "one"))
+
+	re := regexp.MustCompile(`^dropped (\S+): PUT (\S+): 503 Service Unavailable: SlowDown$`)
+	if len(errs) != 1 || !re.MatchString(errs[0]) {
+		t.Errorf("wrong errors: %q", errs)
+	}
+	if len(f.requests) != 2 {
+		t.Errorf("sent %d requests", len(f.requests))
+	}
+}
@@ -0,0 +1,98 @@
+package s3
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Sign the request with AWS Signature Version 4; all headers that are set are
+// signed, so this should be called after setting them.
+func (s *S3) sign(r *http.Request, body []byte, now time.Time) {
+	sum := sha256.Sum256(body)
+	var (
+		hash    = hex.EncodeToString(sum[:])
+		amzDate = now.UTC().Format("20060102T150405Z")
+		scope   = amzDate[:8] + "/" + s.Region + "/s3/aws4_request"
+	)
+	r.Header.Set("X-Amz-Date", amzDate)
+	r.Header.Set("X-Amz-Content-Sha256", hash)
+	if s.SessionToken != "" {
+		r.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	host := r.Host
+	if host == "" {
+		host = r.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for k, v := range r.Header {
+		vals := make([]string, 0, len(v))
+		for _, vv := range v {
+			vals = append(vals, strings.Join(strings.Fields(vv), " "))
+		}
+		headers[strings.ToLower(k)] = strings.Join(vals, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	query := r.URL.Query()
+	params := make([]string, 0, len(query))
+	for k, v := range query {
+		for _, vv := range v {
+			params = append(params, escape(k, false)+"="+escape(vv, false))
+		}
+	}
+	sort.Strings(params)
+
+	path := r.URL.Path
+	if path == "" {
+		path = "/"
+	}
+	creq := new(strings.Builder)
+	fmt.Fprintf(creq, "%s\n%s\n%s\n", r.Method, escape(path, true), strings.Join(params, "&"))
+	for _, k := range names {
+		fmt.Fprintf(creq, "%s:%s\n", k, headers[k])
+	}
+	fmt.Fprintf(creq, "\n%s\n%s", strings.Join(names, ";"), hash)
+
+	sum = sha256.Sum256([]byte(creq.String()))
+	sts := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+
+	key := []byte("AWS4" + s.SecretKey)
+	for _, p := range []string{amzDate[:8], s.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, p)
+	}
+	r.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, strings.Join(names, ";"), hex.EncodeToString(hmacSHA256(key, sts))))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// Escape s the way SigV4 wants it: everything except the unreserved characters
+// is percent-encoded, and / is kept if path is set.
+func escape(s string, path bool) string {
+	b := new(strings.Builder)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '.' || c == '_' || c == '~' || (path && c == '/') {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
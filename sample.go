@@ -0,0 +1,45 @@
+package follow
+
+import "math/rand"
+
+// Sample sends only some lines, for when a representative part of a busy file
+// is enough.
+//
+// For example, to send every 100th line:
+//
+//	f.Sample = &follow.Sample{Every: 100}
+//
+// Or about 1% of lines, picked at random:
+//
+//	f.Sample = &follow.Sample{Probability: 0.01}
+//
+// Errors are always sent, and lines that are dropped by sampling aren't seen by
+// Alerts. The same Sample can be used for several followers; Every counts the
+// lines for every Follower separately.
+type Sample struct {
+	// Send one of every Every lines, starting with the first line.
+	//
+	// Default is 0, which means every line is sent.
+	Every int
+
+	// Send lines with this probability, from 0 to 1.
+	//
+	// Default is 0, which means every line is sent.
+	Probability float64
+}
+
+// Report if this line should be sent; n is the number of lines seen so far,
+// modulo Every.
+func (s *Sample) keep(n *int) bool {
+	if s.Every > 1 {
+		first := *n == 0
+		*n = (*n + 1) % s.Every
+		if !first {
+			return false
+		}
+	}
+	if s.Probability > 0 && rand.Float64() >= s.Probability {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package follow
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSample(t *testing.T) {
+	t.Run("every", func(t *testing.T) {
+		var (
+			s   = &Sample{Every: 3}
+			n   int
+			got []int
+		)
+		for i := 1; i <= 7; i++ {
+			if s.keep(&n) {
+				got = append(got, i)
+			}
+		}
+		if want := []int{1, 4, 7}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v; want %v", got, want)
+		}
+	})
+
+	for _, p := range []float64{0.5, 1} {
+		t.Run(fmt.Sprintf("probability_%v", p), func(t *testing.T) {
+			var (
+				s    = &Sample{Probability: p}
+				n, c int
+			)
+			for i := 0; i < 10_000; i++ {
+				if s.keep(&c) {
+					n++
+				}
+			}
+			if want := int(p * 10_000); n < want-500 || n > want+500 {
+				t.Errorf("sent %d lines; want about %d", n, want)
+			}
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		f := New()
+		f.Data = make(chan Data, 10)
+		f.Sample = &Sample{Every: 100}
+		f.send(Data{Bytes: []byte("1")})
+		f.send(Data{Bytes: []byte("2")})
+		f.send(Data{Err: fmt.Errorf("oops")})
+		if len(f.Data) != 2 {
+			t.Errorf("sent %d", len(f.Data))
+		}
+	})
+}
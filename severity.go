@@ -0,0 +1,62 @@
+package follow
+
+import "bytes"
+
+// Severity is a cheap filter that looks for severity tokens in lines, so that
+// lines can be skipped before running more expensive stages such as a Parser
+// or Alerts.
+//
+// For example, for lines that start with the level:
+//
+//	f.Severity = &follow.Severity{Tokens: []string{"ERROR", "WARN"}}
+//
+// Or for lines that have the level after an RFC 3339 timestamp:
+//
+//	f.Severity = &follow.Severity{Tokens: []string{"ERROR"}, Offset: 21}
+type Severity struct {
+	// Tokens to look for; lines without any of these tokens are skipped.
+	Tokens []string
+
+	// Byte offset where the token must start; default is 0, which means the
+	// line must start with the token.
+	Offset int
+
+	// Look for the token in the first Search bytes of the line, instead of at
+	// a fixed offset. Offset is ignored if this is set.
+	Search int
+}
+
+// Report if l has one of the tokens. The tokens are converted to []byte once
+// and stored in tokens, which is kept in the Follower so that the same
+// Severity can be used for several followers.
+func (s *Severity) match(tokens *[][]byte, l []byte) bool {
+	if *tokens == nil {
+		*tokens = make([][]byte, 0, len(s.Tokens))
+		for _, t := range s.Tokens {
+			*tokens = append(*tokens, []byte(t))
+		}
+	}
+
+	if s.Search > 0 {
+		if len(l) > s.Search {
+			l = l[:s.Search]
+		}
+		for _, t := range *tokens {
+			if bytes.Contains(l, t) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if s.Offset > len(l) {
+		return false
+	}
+	l = l[s.Offset:]
+	for _, t := range *tokens {
+		if bytes.HasPrefix(l, t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package follow
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"testing"
+)
+
+func TestSeverity(t *testing.T) {
+	tests := []struct {
+		s    Severity
+		line string
+		want bool
+	}{
+		{Severity{Tokens: []string{"ERROR", "WARN"}}, "ERROR oh noes", true},
+		{Severity{Tokens: []string{"ERROR", "WARN"}}, "WARN hmm", true},
+		{Severity{Tokens: []string{"ERROR", "WARN"}}, "INFO ERROR", false},
+		{Severity{Tokens: []string{"ERROR"}}, "", false},
+		{Severity{Tokens: []string{"ERROR"}, Offset: 6}, "12:00 ERROR x", true},
+		{Severity{Tokens: []string{"ERROR"}, Offset: 6}, "12:00 INFO x", false},
+		{Severity{Tokens: []string{"ERROR"}, Offset: 60}, "12:00 ERROR x", false},
+		{Severity{Tokens: []string{"ERROR"}, Search: 12}, "12:00 ERROR x", true},
+		{Severity{Tokens: []string{"ERROR"}, Search: 8}, "12:00 ERROR x", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%q", tt.line), func(t *testing.T) {
+			var tokens [][]byte
+			got := tt.s.match(&tokens, []byte(tt.line))
+			if got != tt.want {
+				t.Errorf("got %t; want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+// Lines where 1 in 100 is an error.
+func severityData() []byte {
+	var b bytes.Buffer
+	for i := 0; i < 10_000; i++ {
+		lvl := "INFO"
+		if i%100 == 0 {
+			lvl = "ERROR"
+		}
+		fmt.Fprintf(&b, `level=%s msg="request handled" path=/some/path/%d duration=42ms`+"\n", lvl, i)
+	}
+	return b.Bytes()
+}
+
+func BenchmarkSeverity(b *testing.B) {
+	data := severityData()
+	var (
+		alert = &Alert{
+			Match: regexp.MustCompile(`level=ERROR .*duration=\d+ms`),
+			Func:  func(Data) {},
+		}
+		s = new(alertState)
+	)
+
+	b.Run("regexp", func(b *testing.B) {
+		f := New()
+		f.Parser = Logfmt{}
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			for _, l := range f.process(data) {
+				alert.run(s, l)
+			}
+		}
+	})
+
+	b.Run("severity", func(b *testing.B) {
+		f := New()
+		f.Parser = Logfmt{}
+		f.Severity = &Severity{Tokens: []string{"ERROR"}, Offset: len("level=")}
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			for _, l := range f.process(data) {
+				alert.run(s, l)
+			}
+		}
+	})
+}
@@ -0,0 +1,31 @@
+package follow
+
+import "os/signal"
+
+// ReopenOnSignal reopens the file of all the followers when the process gets
+// SIGHUP or SIGUSR1, which is what logrotate's postrotate usually sends:
+//
+//	postrotate
+//		kill -HUP $(cat /run/app.pid)
+//	endscript
+//
+// Errors from reopening are sent on Data, as with Reopen.
+//
+// The returned function stops relaying the signals. Note this calls
+// signal.Stop on Reopen, so it also stops any other signals you passed to
+// signal.Notify for it.
+//
+// This does nothing on Windows.
+func ReopenOnSignal(f ...*Follower) (stop func()) {
+	if len(reopenSignals) == 0 {
+		return func() {}
+	}
+	for _, ff := range f {
+		signal.Notify(ff.Reopen, reopenSignals...)
+	}
+	return func() {
+		for _, ff := range f {
+			signal.Stop(ff.Reopen)
+		}
+	}
+}
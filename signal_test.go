@@ -0,0 +1,46 @@
+//go:build !windows
+
+package follow
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReopenOnSignal(t *testing.T) {
+	var events chan Event
+	f, tmp, lines := startWith(context.Background(), t, func(f *Follower) {
+		events = make(chan Event, 10)
+		f.Events = events
+	})
+	stop := ReopenOnSignal(f)
+	defer stop()
+
+	for _, sig := range []syscall.Signal{syscall.SIGHUP, syscall.SIGUSR1} {
+		err := syscall.Kill(syscall.Getpid(), sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+	wait:
+		for {
+			select {
+			case e := <-events:
+				if e.Kind == EventReopen {
+					break wait
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("no EventReopen for %s", sig)
+			}
+		}
+		write(t, tmp, sig.String())
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	f.Stop()
+	got := <-lines
+	if len(got) != 2 || got[0] != "hangup" || got[1] != "user defined signal 1" {
+		t.Errorf("%q", got)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package follow
+
+import (
+	"os"
+	"syscall"
+)
+
+var reopenSignals = []os.Signal{syscall.SIGHUP, syscall.SIGUSR1}
@@ -0,0 +1,6 @@
+package follow
+
+import "os"
+
+// Windows doesn't have SIGHUP or SIGUSR1; only os.Interrupt and os.Kill.
+var reopenSignals []os.Signal
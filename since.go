@@ -0,0 +1,148 @@
+package follow
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"regexp"
+	"time"
+)
+
+// Since starts reading at the first line logged at or after Time, for example
+// to get everything since 14:32:
+//
+//	f.Since = &follow.Since{
+//		Time:   time.Date(2024, 5, 1, 14, 32, 0, 0, time.Local),
+//		Match:  regexp.MustCompile(`^\S+ \S+`),
+//		Layout: "2006-01-02 15:04:05",
+//	}
+//
+// This implies Follower.FromStart. Log files are expected to be (mostly) in
+// order: the start is found with a binary search, rather than reading
+// everything before it, and every line after the first one at or after Time is
+// sent, even if it's older or doesn't have a timestamp. Set Skew for logs that
+// are a bit out of order, for example because several processes write to it.
+//
+// Data.Line counts from the first line that was read, rather than the start of
+// the file.
+type Since struct {
+	Time time.Time
+
+	// Find the timestamp in a line; the first submatch is used if there is
+	// one, or the entire match otherwise.
+	//
+	// Default is nil, which means Record.Timestamp from Follower.Parser is
+	// used.
+	Match *regexp.Regexp
+
+	// Layout for the timestamp; see time.Parse.
+	//
+	// Default is time.RFC3339.
+	Layout string
+
+	// Location for timestamps without a time zone.
+	//
+	// Default is nil, which means time.Local.
+	Location *time.Location
+
+	// Allow lines to be out of order by this much. The binary search looks
+	// for Time minus Skew, so it doesn't skip lines that are after a line
+	// that's a bit older, and lines before Time are dropped until there is a
+	// line at or after Time plus Skew; every line after that is sent.
+	//
+	// Default is 0, which means lines are expected to be in order.
+	Skew time.Duration
+}
+
+// Get the timestamp from a line, if it has one.
+func (f *Follower) lineTime(l []byte) (time.Time, bool) {
+	s := f.Since
+	if s.Match == nil {
+		if f.Parser == nil {
+			return time.Time{}, false
+		}
+		rec, err := f.Parser.Parse(l)
+		return rec.Timestamp, err == nil && !rec.Timestamp.IsZero()
+	}
+	return Timestamp{Match: s.Match, Layout: s.Layout, Location: s.Location}.find(l)
+}
+
+// Report if d should be sent: lines at or after Since.Time, and every line
+// starting with the first one at or after Since.Time plus Since.Skew. Lines
+// without a timestamp are sent if the line before it was.
+func (f *Follower) since(d Data) bool {
+	if f.sinceFound {
+		return true
+	}
+	t, ok := d.Timestamp, !d.Timestamp.IsZero()
+	if f.Since.Match != nil {
+		t, ok = f.lineTime(d.Bytes)
+	}
+	if !ok {
+		return f.sinceKeep
+	}
+	f.sinceKeep = !t.Before(f.Since.Time)
+	f.sinceFound = !t.Before(f.Since.Time.Add(f.Since.Skew))
+	return f.sinceKeep
+}
+
+// Find the offset of the first line at or after Since.Time minus Since.Skew
+// with a binary search. This may be a bit before it; the lines in between are skipped by
+// since.
+func (f *Follower) seekSince(fp *os.File) (int64, error) {
+	if f.Encoding != nil { // Can't look at undecoded data.
+		return 0, nil
+	}
+	st, err := fp.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		lo, hi = int64(0), st.Size()
+		buf    = make([]byte, 64*1024)
+		target = f.Since.Time.Add(-f.Since.Skew)
+	)
+	for hi-lo > int64(len(buf)) {
+		start, end, t, ok := f.lineAt(fp, buf, lo+(hi-lo)/2, hi)
+		if !ok {
+			break
+		}
+		if t.Before(target) {
+			lo = end
+		} else {
+			hi = start
+		}
+	}
+	f.debug("found start for Since", "offset", lo)
+	return lo, nil
+}
+
+// Find the first line with a timestamp that starts after from and before to,
+// returning its start and end offset.
+func (f *Follower) lineAt(fp *os.File, buf []byte, from, to int64) (start, end int64, t time.Time, ok bool) {
+	n, err := fp.ReadAt(buf, from-1)
+	if err != nil && err != io.EOF {
+		return 0, 0, t, false
+	}
+
+	// Skip the line from is in, unless it's at the start of a line.
+	b := buf[:n]
+	i := bytes.IndexByte(b, '\n')
+	if i == -1 {
+		return 0, 0, t, false
+	}
+	b, start = b[i+1:], from+int64(i)
+	for start < to {
+		i := bytes.IndexByte(b, '\n')
+		if i == -1 {
+			return 0, 0, t, false
+		}
+		end = start + int64(i) + 1
+		if t, ok = f.lineTime(b[:i]); ok {
+			return start, end, t, true
+		}
+		b, start = b[i+1:], end
+	}
+	return 0, 0, t, false
+}
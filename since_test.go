@@ -0,0 +1,198 @@
+package follow
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSince(t *testing.T) {
+	start := time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+	var b strings.Builder
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&b, "%s line %d\n", start.Add(time.Duration(i)*time.Second).Format(time.RFC3339), i)
+		if i%100 == 0 {
+			b.WriteString("  continued\n")
+		}
+	}
+	tmp := filepath.Join(t.TempDir(), "f")
+	err := os.WriteFile(tmp, []byte(b.String()), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		since time.Duration
+		first string
+		lines int
+	}{
+		{-time.Hour, " line 0", 20200},
+		{0, " line 0", 20200},
+		{15000 * time.Second, " line 15000", 5050},
+		{15000*time.Second + time.Millisecond, " line 15001", 5048},
+		{20000 * time.Second, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.since), func(t *testing.T) {
+			f := New()
+			f.Data = make(chan Data, 30000)
+			f.NoFollow = true
+			f.Since = &Since{
+				Time:  start.Add(tt.since),
+				Match: regexp.MustCompile(`^\S+`),
+			}
+			err := f.Start(context.Background(), tmp)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var (
+				first string
+				n     int
+			)
+			for d := range f.Data {
+				if d.Err == io.EOF {
+					break
+				}
+				if d.Err != nil {
+					t.Fatal(d.Err)
+				}
+				if n == 0 {
+					first = d.String()
+				}
+				n++
+			}
+			if !strings.HasSuffix(first, tt.first) {
+				t.Errorf("first line %q; want %q", first, tt.first)
+			}
+			if n != tt.lines {
+				t.Errorf("%d lines; want %d", n, tt.lines)
+			}
+			if st := f.Stats(); tt.since > 0 && st.Bytes > int64(b.Len())/2 {
+				t.Errorf("read %d bytes of %d", st.Bytes, b.Len())
+			}
+		})
+	}
+}
+
+func TestSinceParser(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "f")
+	err := os.WriteFile(tmp, []byte(""+
+		"2024-05-01T14:31:00Z stdout F one\n"+
+		"2024-05-01T14:32:00Z stdout F two\n"+
+		"2024-05-01T14:31:30Z stdout F three\n"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := New()
+	f.Data = make(chan Data, 10)
+	f.NoFollow = true
+	f.Parser = CRI{}
+	f.Since = &Since{Time: time.Date(2024, 5, 1, 14, 32, 0, 0, time.UTC)}
+	err = f.Start(context.Background(), tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for d := range f.Data {
+		if d.Err == io.EOF {
+			break
+		}
+		got = append(got, d.String())
+	}
+	if want := "[two three]"; fmt.Sprint(got) != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
+
+// The same Since can be used for more than one follower.
+func TestSinceShared(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "f")
+	err := os.WriteFile(tmp, []byte(""+
+		"2024-05-01T14:31:00Z one\n"+
+		"2024-05-01T14:32:00Z two\n"+
+		"2024-05-01T14:33:00Z three\n"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	since := &Since{
+		Time:  time.Date(2024, 5, 1, 14, 32, 0, 0, time.UTC),
+		Match: regexp.MustCompile(`^\S+`),
+	}
+	for i := 0; i < 2; i++ {
+		f := New()
+		f.Data = make(chan Data, 10)
+		f.NoFollow = true
+		f.Since = since
+		err = f.Start(context.Background(), tmp)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for d := range f.Data {
+			if d.Err == io.EOF {
+				break
+			}
+			got = append(got, strings.Fields(d.String())[1])
+		}
+		if want := "[two three]"; fmt.Sprint(got) != want {
+			t.Errorf("follower %d: got %s; want %s", i, got, want)
+		}
+	}
+}
+
+// Lines that are a bit out of order aren't skipped by the binary search, and
+// older lines are dropped until the log is past Time plus Skew.
+func TestSinceSkew(t *testing.T) {
+	var (
+		start = time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+		since = start.Add(15000 * time.Second)
+		b     strings.Builder
+		want  int
+	)
+	for i := 0; i < 20000; i++ {
+		ts := start.Add(time.Duration(i+(i*37)%7-3) * time.Second)
+		if !ts.Before(since) {
+			want++
+		}
+		fmt.Fprintf(&b, "%s line %d\n", ts.Format(time.RFC3339), i)
+	}
+	tmp := filepath.Join(t.TempDir(), "f")
+	err := os.WriteFile(tmp, []byte(b.String()), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := New()
+	f.Data = make(chan Data, 30000)
+	f.NoFollow = true
+	f.Since = &Since{Time: since, Match: regexp.MustCompile(`^\S+`), Skew: 10 * time.Second}
+	err = f.Start(context.Background(), tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	for d := range f.Data {
+		if d.Err == io.EOF {
+			break
+		}
+		if ts, _ := time.Parse(time.RFC3339, strings.Fields(d.String())[0]); ts.Before(since) {
+			t.Errorf("line before Since.Time: %q", d)
+		}
+		n++
+	}
+	if n != want {
+		t.Errorf("got %d lines; want %d", n, want)
+	}
+}
@@ -0,0 +1,69 @@
+package follow
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Snapshot describes data copied with Follower.Snapshot.
+type Snapshot struct {
+	File   string // File name, as passed to Start.
+	Start  int64  // Byte range that was copied.
+	End    int64
+	SHA256 string // Hex-encoded checksum of the copied data.
+}
+
+// Snapshot copies the file from the start up to the last complete line to w.
+//
+// This opens the file separately, so it doesn't affect following the file. A
+// trailing line without a newline is never copied, as it may be partially
+// written.
+func (f *Follower) Snapshot(w io.Writer) (Snapshot, error) {
+	f.fpMu.Lock()
+	name, file := f.name, f.file
+	f.fpMu.Unlock()
+
+	fp, err := os.Open(file)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("follow.Snapshot: %w", err)
+	}
+	defer fp.Close()
+	st, err := fp.Stat()
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("follow.Snapshot: %w", err)
+	}
+
+	var (
+		h       = sha256.New()
+		out     = io.MultiWriter(w, h)
+		r       = io.LimitReader(fp, st.Size())
+		buf     = make([]byte, 64*1024)
+		pending []byte
+		n       int64
+	)
+	for {
+		read, err := r.Read(buf)
+		if read > 0 {
+			pending = append(pending, buf[:read]...)
+			if i := bytes.LastIndexByte(pending, '\n'); i > -1 {
+				if _, err := out.Write(pending[:i+1]); err != nil {
+					return Snapshot{}, fmt.Errorf("follow.Snapshot: %w", err)
+				}
+				n += int64(i + 1)
+				pending = append(pending[:0], pending[i+1:]...)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Snapshot{}, fmt.Errorf("follow.Snapshot: %w", err)
+		}
+	}
+
+	return Snapshot{File: name, Start: 0, End: n, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
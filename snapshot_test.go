@@ -0,0 +1,38 @@
+package follow
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	f, tmp, lines := start(context.Background(), t)
+	write(t, tmp, "one", "two")
+	appendString(t, tmp, "partial")
+
+	buf := new(bytes.Buffer)
+	s, err := f.Snapshot(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "one\ntwo\n"
+	if buf.String() != want {
+		t.Errorf("wrong data: %q", buf.String())
+	}
+	sum := sha256.Sum256([]byte(want))
+	if s.File != tmp || s.Start != 0 || s.End != 8 || s.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("wrong snapshot: %#v", s)
+	}
+
+	// Still following.
+	write(t, tmp, "", "three")
+	f.Stop()
+	got := <-lines
+	if len(got) != 4 || got[2] != "partial" || got[3] != "three" {
+		t.Errorf("wrong lines: %q", got)
+	}
+}
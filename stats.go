@@ -0,0 +1,73 @@
+package follow
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats are counters for a Follower, since it was created.
+type Stats struct {
+	Bytes       int64 `json:"bytes"`       // Bytes read from the file.
+	Lines       int64 `json:"lines"`       // Lines sent on Data.
+	Errors      int64 `json:"errors"`      // Errors, other than io.EOF.
+	Dropped     int64 `json:"dropped"`     // Lines dropped by Overflow.
+	Filtered    int64 `json:"filtered"`    // Lines dropped by Filter.
+	Reopens     int64 `json:"reopens"`     // File was reopened after a signal, or after it reappeared.
+	Rotations   int64 `json:"rotations"`   // File was rotated or removed.
+	Truncations int64 `json:"truncations"` // File was truncated.
+	Skipped     int64 `json:"skipped"`     // Bytes left in the file when MaxLines or MaxBytes was reached.
+
+	// Bytes written to the file that weren't read yet; this is only set
+	// while following a file.
+	Lag int64 `json:"lag"`
+
+	LastRead  time.Time `json:"last_read"`  // Time data was last read.
+	LastEvent time.Time `json:"last_event"` // Time of the last lifecycle event (see Event), except EventIdle.
+}
+
+type stats struct {
+	mu sync.Mutex
+	s  Stats
+}
+
+// Stats returns the current counters. It's safe to call this from any
+// goroutine.
+func (f *Follower) Stats() Stats {
+	f.stats.mu.Lock()
+	s := f.stats.s
+	f.stats.mu.Unlock()
+	s.Dropped = atomic.LoadInt64(f.dropped)
+
+	f.fpMu.Lock()
+	defer f.fpMu.Unlock()
+	if f.state == stateFollowing && f.fp != nil {
+		if st, err := f.fp.Stat(); err == nil && st.Size() > f.offset+int64(len(f.partial)) {
+			s.Lag = st.Size() - f.offset - int64(len(f.partial))
+		}
+	}
+	return s
+}
+
+func (f *Follower) count(fn func(*Stats)) {
+	f.stats.mu.Lock()
+	fn(&f.stats.s)
+	f.stats.mu.Unlock()
+}
+
+func (f *Follower) countEvent(k EventKind) {
+	if k == EventIdle {
+		return
+	}
+	f.count(func(s *Stats) {
+		s.LastEvent = time.Now()
+		switch k {
+		case EventReopen, EventReappear:
+			s.Reopens++
+		case EventRotate, EventRemove:
+			s.Rotations++
+		case EventTruncate:
+			s.Truncations++
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package follow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	f, tmp, lines := start(context.Background(), t)
+	write(t, tmp, "one", "two")
+	err := os.Truncate(tmp, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	write(t, tmp, "three")
+	f.Stop()
+	<-lines
+
+	s := f.Stats()
+	if s.LastRead.IsZero() || s.LastEvent.IsZero() {
+		t.Errorf("LastRead or LastEvent not set: %#v", s)
+	}
+	s.LastRead, s.LastEvent = time.Time{}, time.Time{}
+	if want := (Stats{Bytes: 14, Lines: 3, Truncations: 1}); s != want {
+		t.Errorf("\ngot:  %#v\nwant: %#v", s, want)
+	}
+}
+
+func TestStatsLag(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "f")
+	touch(t, tmp)
+
+	f := New()
+	f.ReadSize = 4
+	err := f.Go(context.Background(), tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.stopAndDrain()
+
+	// Reads "aaaa", then "\nbbb", and then blocks on sending "aaaa".
+	write(t, tmp, "aaaa", "bbbb")
+	if lag := f.Stats().Lag; lag != 2 {
+		t.Errorf("Lag = %d; want 2", lag)
+	}
+	for i := 0; i < 2; i++ {
+		<-f.Data
+	}
+	if lag := f.Stats().Lag; lag != 0 {
+		t.Errorf("Lag = %d; want 0", lag)
+	}
+}
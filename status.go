@@ -0,0 +1,72 @@
+package follow
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type state uint8
+
+const (
+	stateNew state = iota
+	stateFollowing
+	stateWaiting
+	stateStopped
+)
+
+func (s state) String() string {
+	switch s {
+	case stateFollowing:
+		return "following"
+	case stateWaiting:
+		return "waiting"
+	case stateStopped:
+		return "stopped"
+	}
+	return "new"
+}
+
+// Current status of a Follower, for String and MarshalJSON.
+type status struct {
+	File   string `json:"file"`   // As passed to Start.
+	Path   string `json:"path"`   // Absolute path.
+	State  string `json:"state"`  // new, following, waiting, or stopped.
+	Offset int64  `json:"offset"` // Offset of the next byte to read.
+	Line   int64  `json:"line"`   // Number of the last complete line.
+	Stats  Stats  `json:"stats"`  // See Follower.Stats.
+}
+
+func (f *Follower) status() status {
+	st := f.Stats()
+	f.fpMu.Lock()
+	defer f.fpMu.Unlock()
+	return status{
+		File:   f.name,
+		Path:   f.file,
+		State:  f.state.String(),
+		Offset: f.offset + int64(len(f.partial)),
+		Line:   f.lineno,
+		Stats:  st,
+	}
+}
+
+// String describes the current file, state, offset, and the main Stats, for
+// example:
+//
+//	follow.Follower{"/var/log/messages" following at offset 1024, line 42; 40 lines sent, 0 errors, 2 dropped, 5 filtered, lag 0}
+func (f *Follower) String() string {
+	s := f.status()
+	return fmt.Sprintf("follow.Follower{%q %s at offset %d, line %d; %d lines sent, %d errors, %d dropped, %d filtered, lag %d}",
+		s.File, s.State, s.Offset, s.Line, s.Stats.Lines, s.Stats.Errors, s.Stats.Dropped, s.Stats.Filtered, s.Stats.Lag)
+}
+
+// MarshalJSON writes the current file, state, offset, and Stats as JSON:
+//
+//	{"file": "messages", "path": "/var/log/messages", "state": "following", "offset": 1024,
+//	 "line": 42, "stats": {"bytes": 1024, "lines": 40, ...}}
+//
+// The state is one of "new", "following", "waiting" (for the file to
+// reappear), or "stopped".
+func (f *Follower) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.status())
+}
@@ -0,0 +1,60 @@
+package follow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStatus(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "f")
+	err := os.WriteFile(tmp, []byte("existing\n"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := New()
+	if s := f.String(); s != `follow.Follower{"" new at offset 0, line 0; 0 lines sent, 0 errors, 0 dropped, 0 filtered, lag 0}` {
+		t.Errorf("wrong string: %s", s)
+	}
+
+	stop := f.Stop
+	done := make(chan struct{})
+	go func() {
+		f.Start(context.Background(), tmp)
+		close(done)
+	}()
+	<-f.Ready
+
+	write(t, tmp, "one", "two")
+	<-f.Data
+	<-f.Data
+	appendString(t, tmp, "partial")
+
+	want := fmt.Sprintf(`follow.Follower{%q following at offset 24, line 2; 2 lines sent, 0 errors, 0 dropped, 0 filtered, lag 0}`, tmp)
+	if s := f.String(); s != want {
+		t.Errorf("\ngot:  %s\nwant: %s", s, want)
+	}
+
+	j, err := json.Marshal(&f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The times in Stats are different every time.
+	want = fmt.Sprintf(`{"file":%[1]q,"path":%[1]q,"state":"following","offset":24,"line":2,`+
+		`"stats":{"bytes":15,"lines":2,"errors":0,"dropped":0,"filtered":0,"reopens":0,"rotations":0,"truncations":0,"skipped":0,"lag":0,`, tmp)
+	if !strings.HasPrefix(string(j), want) {
+		t.Errorf("\ngot:  %s\nwant: %s", j, want)
+	}
+
+	go stop()
+	<-f.Data
+	<-done
+	if s := f.String(); s != fmt.Sprintf(`follow.Follower{%q stopped at offset 24, line 2; 2 lines sent, 0 errors, 0 dropped, 0 filtered, lag 0}`, tmp) {
+		t.Errorf("wrong string: %s", s)
+	}
+}
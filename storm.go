@@ -0,0 +1,66 @@
+package follow
+
+import (
+	"context"
+	"time"
+)
+
+// Storm detects rotation storms: a file that's rotated (or removed) many times
+// in a short period, usually because of a misconfigured logger. Reopening the
+// file over and over again wastes CPU, so we wait Cooldown before reopening the
+// file.
+//
+// For example, to wait 10 seconds if the file was rotated more than 5 times in
+// one second:
+//
+//	f.Storm = &follow.Storm{Rotations: 5, Period: time.Second, Cooldown: 10 * time.Second}
+//
+// An EventStorm is sent before waiting. The same Storm can be used for several
+// followers; rotations are counted for every Follower separately.
+type Storm struct {
+	// It's a storm if there are more than Rotations in Period.
+	Rotations int
+	Period    time.Duration
+
+	// Time to wait before reopening the file.
+	Cooldown time.Duration
+}
+
+// Record a rotation in rotations and report if this is a storm.
+func (s *Storm) rotated(rotations *[]time.Time, now time.Time) bool {
+	keep := (*rotations)[:0]
+	for _, t := range *rotations {
+		if now.Sub(t) < s.Period {
+			keep = append(keep, t)
+		}
+	}
+	*rotations = append(keep, now)
+
+	if len(*rotations) <= s.Rotations {
+		return false
+	}
+	*rotations = (*rotations)[:0]
+	return true
+}
+
+// Wait for the cooldown if there's a rotation storm; returns false if the
+// context was cancelled or Stop called.
+func (f *Follower) stormWait(ctx context.Context) bool {
+	if f.Storm == nil || !f.Storm.rotated(&f.rotations, time.Now()) {
+		return true
+	}
+
+	f.event(EventStorm)
+	f.sendEvents()
+
+	t := time.NewTimer(f.Storm.Cooldown)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-f.done:
+		return false
+	case <-t.C:
+		return true
+	}
+}
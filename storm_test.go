@@ -0,0 +1,73 @@
+package follow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStorm(t *testing.T) {
+	var (
+		s         = &Storm{Rotations: 2, Period: time.Second}
+		rotations []time.Time
+		now       = time.Now()
+	)
+
+	tests := []struct {
+		at   time.Duration
+		want bool
+	}{
+		{0, false},
+		{100 * time.Millisecond, false},
+		{200 * time.Millisecond, true},
+		{300 * time.Millisecond, false}, // Reset after a storm.
+		{2 * time.Second, false},        // Outside of Period.
+		{2100 * time.Millisecond, false},
+		{2200 * time.Millisecond, true},
+	}
+	for _, tt := range tests {
+		if got := s.rotated(&rotations, now.Add(tt.at)); got != tt.want {
+			t.Errorf("%s: got %t; want %t", tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestStormEvent(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "f")
+	touch(t, tmp)
+
+	f := New()
+	f.Storm = &Storm{Rotations: 1, Period: time.Second, Cooldown: 100 * time.Millisecond}
+	f.Events = make(chan Event, 20)
+	stop := f.Stop
+	go f.Start(context.Background(), tmp)
+	<-f.Ready
+
+	for i := 0; i < 2; i++ {
+		err := os.Rename(tmp, tmp+".1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		touch(t, tmp)
+		time.Sleep(50 * time.Millisecond)
+	}
+	time.Sleep(200 * time.Millisecond)
+	write(t, tmp, "after")
+	if d := <-f.Data; d.String() != "after" {
+		t.Errorf("wrong line: %q", d)
+	}
+	go stop()
+	<-f.Data
+
+	var got []EventKind
+	for len(f.Events) > 0 {
+		got = append(got, (<-f.Events).Kind)
+	}
+	want := []EventKind{EventOpen, EventRotate, EventReappear, EventRotate, EventStorm, EventReappear}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %v\nwant: %v", got, want)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Print shell completions for all flags; filenames are completed by default.
+func completions(w io.Writer, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %s completions bash|zsh|fish", progname())
+	}
+
+	name := progname()
+	var flags []*flag.Flag
+	flag.VisitAll(func(f *flag.Flag) { flags = append(flags, f) })
+
+	switch args[0] {
+	default:
+		return fmt.Errorf("unknown shell: %q; supported are bash, zsh, fish", args[0])
+
+	case "bash":
+		var names []string
+		for _, f := range flags {
+			names = append(names, "-"+f.Name)
+		}
+		fmt.Fprintf(w, "_%[1]s() {\n"+
+			"\tlocal cur=${COMP_WORDS[COMP_CWORD]}\n"+
+			"\tif [[ $COMP_CWORD -eq 1 ]]; then\n"+
+			"\t\tCOMPREPLY=($(compgen -W 'completions ls' -- \"$cur\"))\n"+
+			"\tfi\n"+
+			"\tif [[ $cur == -* ]]; then\n"+
+			"\t\tCOMPREPLY=($(compgen -W '%[2]s' -- \"$cur\"))\n"+
+			"\tfi\n"+
+			"}\n"+
+			"complete -o default -F _%[1]s %[1]s\n",
+			name, strings.Join(names, " "))
+
+	case "zsh":
+		fmt.Fprintf(w, "#compdef %s\n\n_arguments \\\n", name)
+		for _, f := range flags {
+			fmt.Fprintf(w, "\t'-%s[%s]%s' \\\n", f.Name, zshEscape(f.Usage), zshArg(f))
+		}
+		fmt.Fprintf(w, "\t'1: :(completions ls)' \\\n\t'*:file:_files'\n")
+
+	case "fish":
+		fmt.Fprintf(w, "complete -c %s -n __fish_use_subcommand -a 'completions ls'\n", name)
+		for _, f := range flags {
+			req := ""
+			if !isBool(f) {
+				req = " -r"
+			}
+			fmt.Fprintf(w, "complete -c %s -o %s%s -d %s\n", name, f.Name, req, fishQuote(f.Usage))
+		}
+	}
+	return nil
+}
+
+func progname() string { return filepath.Base(os.Args[0]) }
+
+func isBool(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func zshArg(f *flag.Flag) string {
+	if isBool(f) {
+		return ""
+	}
+	return ":" + f.Name + ":"
+}
+
+func zshEscape(s string) string {
+	return strings.NewReplacer(`'`, `'\''`, `[`, `\[`, `]`, `\]`, `:`, `\:`).Replace(s)
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// List the files that match the globs or are in the directories, with their
+// size and the last time they were written to.
+func ls(w io.Writer, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: %s ls glob-or-dir [glob-or-dir...]", progname())
+	}
+
+	var files []string
+	for _, a := range args {
+		if st, err := os.Stat(a); err == nil && st.IsDir() {
+			a = filepath.Join(a, "*")
+		}
+		m, err := filepath.Glob(a)
+		if err != nil {
+			return fmt.Errorf("%q: %w", a, err)
+		}
+		files = append(files, m...)
+	}
+	sort.Strings(files)
+
+	now := time.Now()
+	for _, f := range files {
+		st, err := os.Stat(f)
+		if err != nil {
+			fmt.Fprintf(w, "%s: %s\n", f, err)
+			continue
+		}
+		if !st.Mode().IsRegular() {
+			continue
+		}
+		fmt.Fprintf(w, "%8s  %-12s  %s\n", size(st.Size()), since(now.Sub(st.ModTime())), f)
+	}
+	return nil
+}
+
+func size(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1fG", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fM", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fK", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", n)
+}
+
+func since(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds ago", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+}
@@ -2,44 +2,245 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"os"
 	"os/signal"
+	"regexp"
 	"syscall"
+	"text/template"
+	"time"
 
 	"zgo.at/follow"
+	"zgo.at/follow/webhook"
 )
 
+// Data passed to -template.
+type record struct {
+	follow.Data
+	File string    // File name as given on the commandline.
+	Time time.Time // Time the line was read.
+	Text string    // Line as a string.
+}
+
 func main() {
-	if len(os.Args) <= 1 {
+	var (
+		trace   = flag.String("trace", "", "record a trace of all events to this file")
+		replay  = flag.Bool("replay", false, "replay a trace recorded with -trace instead of following a file")
+		tpl     = flag.String("template", "", "format every line with this text/template; e.g. '{{.File}} {{.Time.Format \"15:04:05\"}} {{.Text}}'")
+		multi   = flag.String("multiline", "", "join multiline records: java, python, or go")
+		sum     = flag.Bool("summary", false, "print a summary of what was read on exit")
+		events  = flag.Bool("events", false, "print lifecycle events such as rotation and truncation to stderr")
+		match   = flag.String("match", "", "only show lines matching this regexp")
+		exclude = flag.String("exclude", "", "don't show lines matching this regexp")
+		redact  = flag.Bool("redact", false, "hide bearer tokens and credit card numbers")
+		noANSI  = flag.Bool("strip-ansi", false, "remove colours and other ANSI escape sequences")
+		until   = flag.String("until", "", "exit after a line matching this regexp")
+		all     = flag.Bool("from-start", false, "read the existing file from the start, like tail -n +1 -f")
+		once    = flag.Bool("no-follow", false, "exit at the end of the file instead of waiting for more data")
+		file    = flag.Bool("watch-file", false, "watch only the file rather than the directory it's in")
+
+		alert      = flag.String("alert", "", "alert on lines matching this regexp; printed to stderr unless -alert-url is set")
+		alertURL   = flag.String("alert-url", "", "POST alerts to this URL as JSON")
+		alertEvery = flag.Duration("alert-every", 0, "alert at most once for this period")
+		alertDedup = flag.Duration("alert-dedup", 0, "don't alert for identical lines within this period")
+	)
+
+	// Subcommands; use ./ls to follow a file named "ls".
+	if len(os.Args) > 1 {
+		cmd := map[string]func(io.Writer, []string) error{
+			"completions": completions,
+			"ls":          ls,
+		}[os.Args[1]]
+		if cmd != nil {
+			if err := cmd(os.Stdout, os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+
+	flag.Parse()
+	if flag.NArg() == 0 {
 		fmt.Println("need at least one filename")
 		os.Exit(1)
 	}
 
+	var out *template.Template
+	if *tpl != "" {
+		var err error
+		out, err = template.New("").Parse(*tpl + "\n")
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	f := follow.New()
 
 	// Maximum time to retry opening the file after it goes away; -1 to keep
 	// trying forever.
 	f.Retry = -1
+	f.RenderError = errorMessage
+	f.StripANSI = *noANSI
+	f.FromStart = *all
+	f.NoFollow = *once
+	if *file {
+		f.Watch = follow.WatchFile
+	}
 
-	// Install signal handler; any signal sent to this will reopen the file; you
-	// can send something manually with:
-	//    f.Reopen <- os.Interrupt
-	signal.Notify(f.Reopen, syscall.SIGHUP)
+	// Reopen the file on SIGHUP or SIGUSR1, as sent by logrotate; you can
+	// also reopen it manually with:
+	//    f.ReopenFile()
+	follow.ReopenOnSignal(&f)
 
-	// Keep reading data in the background, sending it to the f.Data channel.
-	go func() { log.Fatal(f.Start(context.Background(), os.Args[1])) }()
+	if *multi != "" {
+		m, ok := follow.MultilinePreset(*multi)
+		if !ok {
+			log.Fatalf("unknown -multiline preset: %q", *multi)
+		}
+		f.Multiline = m
+	}
+
+	if *match != "" || *exclude != "" {
+		f.Filter = &follow.Filter{}
+		if *match != "" {
+			re, err := regexp.Compile(*match)
+			if err != nil {
+				log.Fatal(err)
+			}
+			f.Filter.Match = re
+		}
+		if *exclude != "" {
+			re, err := regexp.Compile(*exclude)
+			if err != nil {
+				log.Fatal(err)
+			}
+			f.Filter.Exclude = append(f.Filter.Exclude, re)
+		}
+	}
+
+	if *until != "" {
+		re, err := regexp.Compile(*until)
+		if err != nil {
+			log.Fatal(err)
+		}
+		f.StopOn = re
+	}
+
+	if *redact {
+		f.Transforms = append(f.Transforms, follow.RedactBearer, follow.RedactCard)
+	}
+
+	if *events {
+		f.Events = make(chan follow.Event, 16)
+	}
 
+	summary := newSummary(*sum)
+	if *alert != "" {
+		re, err := regexp.Compile(*alert)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fn := alertFunc(*alertURL)
+		f.Alerts = append(f.Alerts, &follow.Alert{
+			Match: re,
+			Func:  func(d follow.Data) { summary.alert(d); fn(d) },
+			Every: *alertEvery,
+			Dedup: *alertDedup,
+		})
+	}
+
+	if *replay {
+		fp, err := os.Open(flag.Arg(0))
+		if err != nil {
+			log.Fatal(err)
+		}
+		go func() {
+			err := f.Replay(context.Background(), fp)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}()
+	} else {
+		if *trace != "" {
+			fp, err := os.Create(*trace)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer fp.Close()
+			f.Trace = fp
+		}
+
+		// Keep reading data in the background, sending it to the f.Data channel.
+		err := f.Go(context.Background(), flag.Arg(0))
+		if err != nil {
+			log.Fatal(errorMessage(err))
+		}
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+
+	defer func() { summary.print(os.Stderr, f.Stats(), f.Filter) }()
 	for {
-		data := <-f.Data
+		var data follow.Data
+		select {
+		case <-interrupt:
+			return
+		case e := <-f.Events:
+			if e.Kind == follow.EventWaiting {
+				fmt.Fprintf(os.Stderr, "%s: still waiting after %s, attempt %d: %v\n",
+					e.Name, e.Waited.Round(time.Second), e.Attempt, e.Err)
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "%s: %s at offset %d\n", e.Name, e.Kind, e.Offset)
+			continue
+		case data = <-f.Data:
+		}
+
+		summary.add(data)
 		if data.Err != nil {
 			if data.Err == io.EOF {
 				break
 			}
+			summary.print(os.Stderr, f.Stats(), f.Filter)
 			log.Fatal(data.Err)
 		}
+
+		if out != nil {
+			err := out.Execute(os.Stdout, record{Data: data, File: data.Name, Time: data.ReadAt, Text: data.String()})
+			if err != nil {
+				log.Fatal(err)
+			}
+			continue
+		}
 		fmt.Println("X", data)
 	}
 }
+
+func alertFunc(url string) func(follow.Data) {
+	if url == "" {
+		return func(d follow.Data) { fmt.Fprintln(os.Stderr, "alert:", d) }
+	}
+	return webhook.New(url).Alert()
+}
+
+// Show friendlier messages for common errors.
+func errorMessage(err error) string {
+	var we *follow.WatchError
+	if errors.As(err, &we) && we.Hint != "" {
+		return err.Error() + "\n\thint: " + we.Hint
+	}
+
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return "file doesn't exist: " + err.Error()
+	case errors.Is(err, fs.ErrPermission):
+		return "no permission to read the file: " + err.Error()
+	}
+	return err.Error()
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"zgo.at/follow"
+)
+
+// Summary of everything that was read, for -summary.
+//
+// Everything except alerts is counted on the main goroutine; alerts are counted
+// from Alert.Func, which runs on the follower's goroutine.
+type summary struct {
+	start   time.Time
+	files   map[string]*fileSummary
+	alerts  int64 // Atomic.
+	errors  int
+	enabled bool
+}
+
+type fileSummary struct {
+	lines, bytes int
+}
+
+func newSummary(enabled bool) *summary {
+	return &summary{start: time.Now(), files: make(map[string]*fileSummary), enabled: enabled}
+}
+
+func (s *summary) add(d follow.Data) {
+	if !s.enabled {
+		return
+	}
+	if d.Err != nil {
+		if d.Err != io.EOF {
+			s.errors++
+		}
+		return
+	}
+
+	fs, ok := s.files[d.Name]
+	if !ok {
+		fs = &fileSummary{}
+		s.files[d.Name] = fs
+	}
+	fs.lines++
+	fs.bytes += len(d.Bytes)
+}
+
+func (s *summary) alert(follow.Data) {
+	atomic.AddInt64(&s.alerts, 1)
+}
+
+// Print the summary; rotations and truncations come from the follower's Stats,
+// and the number of lines that didn't match from the filter (which may be nil).
+func (s *summary) print(w io.Writer, st follow.Stats, fl *follow.Filter) {
+	if !s.enabled {
+		return
+	}
+
+	names := make([]string, 0, len(s.files))
+	for n := range s.files {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "\nSummary after %s:\n", time.Since(s.start).Round(time.Millisecond))
+	for _, n := range names {
+		fs := s.files[n]
+		fmt.Fprintf(w, "  %s: %d lines, %d bytes\n", n, fs.lines, fs.bytes)
+	}
+	if fl != nil {
+		fmt.Fprintf(w, "  %d lines filtered by -match or -exclude\n", fl.Suppressed())
+	}
+	fmt.Fprintf(w, "  %d rotations, %d truncations, %d alerts, %d errors\n",
+		st.Rotations, st.Truncations, atomic.LoadInt64(&s.alerts), s.errors)
+}
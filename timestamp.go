@@ -0,0 +1,78 @@
+package follow
+
+import (
+	"bytes"
+	"regexp"
+	"time"
+)
+
+// Timestamp parses the time a line was logged from lines that otherwise have
+// no structure, and sets only the Timestamp. This makes Merge, Since, and
+// MaxAge work on plain log files. For example, for a host that logs local time
+// in Amsterdam:
+//
+//	2024-05-01 14:32:00 GET /
+//
+//	ams, _ := time.LoadLocation("Europe/Amsterdam")
+//	f.Parser = follow.Timestamp{
+//		Match:    regexp.MustCompile(`^\S+ \S+`),
+//		Layout:   "2006-01-02 15:04:05",
+//		Location: ams,
+//	}
+//
+// Every Follower has its own Parser, so files from hosts in different time
+// zones can each use their own Location and still be merged in order.
+//
+// Lines without a timestamp, or with one that can't be parsed, get a zero
+// Timestamp rather than an error; Merge sends those after the line before it.
+type Timestamp struct {
+	// Find the timestamp in a line; the first submatch is used if there is
+	// one, or the entire match otherwise.
+	//
+	// Default is nil, which means everything up to the first space.
+	Match *regexp.Regexp
+
+	// Layout for the timestamp; see time.Parse.
+	//
+	// Default is time.RFC3339.
+	Layout string
+
+	// Location for timestamps without a time zone.
+	//
+	// Default is nil, which means time.Local.
+	Location *time.Location
+}
+
+func (t Timestamp) Parse(l []byte) (Record, error) {
+	ts, _ := t.find(l)
+	return Record{Timestamp: ts}, nil
+}
+
+func (t Timestamp) find(l []byte) (time.Time, bool) {
+	var ts []byte
+	if t.Match == nil {
+		ts = l
+		if i := bytes.IndexByte(l, ' '); i > -1 {
+			ts = l[:i]
+		}
+	} else {
+		m := t.Match.FindSubmatch(l)
+		if m == nil {
+			return time.Time{}, false
+		}
+		ts = m[0]
+		if len(m) > 1 {
+			ts = m[1]
+		}
+	}
+
+	layout, loc := t.Layout, t.Location
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	parsed, err := time.ParseInLocation(layout, string(ts), loc)
+	return parsed, err == nil
+}
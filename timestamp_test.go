@@ -0,0 +1,93 @@
+package follow
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestTimestamp(t *testing.T) {
+	ams := time.FixedZone("CEST", 2*3600)
+	tests := []struct {
+		p    Timestamp
+		in   string
+		want time.Time
+	}{
+		{Timestamp{}, "2024-05-01T14:32:00Z GET /", time.Date(2024, 5, 1, 14, 32, 0, 0, time.UTC)},
+		{Timestamp{}, "2024-05-01T14:32:00+02:00", time.Date(2024, 5, 1, 12, 32, 0, 0, time.UTC)},
+		{Timestamp{}, "  continued", time.Time{}},
+		{Timestamp{Match: regexp.MustCompile(`^\S+ \S+`), Layout: "2006-01-02 15:04:05", Location: ams},
+			"2024-05-01 14:32:00 GET /", time.Date(2024, 5, 1, 12, 32, 0, 0, time.UTC)},
+		{Timestamp{Match: regexp.MustCompile(`\[(.+?)\]`), Layout: "02/Jan/2006:15:04:05", Location: time.UTC},
+			"127.0.0.1 [01/May/2024:14:32:00] GET /", time.Date(2024, 5, 1, 14, 32, 0, 0, time.UTC)},
+		{Timestamp{Match: regexp.MustCompile(`\[(.+?)\]`)}, "no brackets", time.Time{}},
+	}
+	for _, tt := range tests {
+		rec, err := tt.p.Parse([]byte(tt.in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rec.Timestamp.Equal(tt.want) {
+			t.Errorf("%q: got %s; want %s", tt.in, rec.Timestamp, tt.want)
+		}
+	}
+}
+
+// Merge files from hosts in different time zones that log local time.
+func TestTimestampMerge(t *testing.T) {
+	var (
+		dir  = t.TempDir()
+		data []<-chan Data
+	)
+	for _, f := range []struct {
+		name, lines string
+		offset      int
+	}{
+		{"ams", "2024-05-01 16:32:01 a1\n2024-05-01 16:32:03 a3\n", 2},
+		{"nyc", "2024-05-01 10:32:02 b2\n2024-05-01 10:32:04 b4\n", -4},
+	} {
+		tmp := filepath.Join(dir, f.name)
+		err := os.WriteFile(tmp, []byte(f.lines), 0666)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fol := New()
+		fol.Data = make(chan Data, 10)
+		fol.FromStart, fol.NoFollow = true, true
+		fol.Parser = Timestamp{
+			Match:    regexp.MustCompile(`^\S+ \S+`),
+			Layout:   "2006-01-02 15:04:05",
+			Location: time.FixedZone(f.name, f.offset*3600),
+		}
+		err = fol.Start(context.Background(), tmp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = append(data, fol.Data)
+	}
+
+	f := New()
+	f.Data = make(chan Data, 10)
+	err := f.Merge(context.Background(), time.Hour, data...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for d := range f.Data {
+		if d.Err == io.EOF {
+			break
+		}
+		got = append(got, d.String())
+	}
+	want := []string{"2024-05-01 16:32:01 a1", "2024-05-01 10:32:02 b2",
+		"2024-05-01 16:32:03 a3", "2024-05-01 10:32:04 b4"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+}
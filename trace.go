@@ -0,0 +1,128 @@
+package follow
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+type traceEvent struct {
+	Time   time.Time `json:"time"`
+	Event  string    `json:"event"`
+	Kind   string    `json:"kind,omitempty"`
+	File   string    `json:"file,omitempty"`
+	Offset int64     `json:"offset,omitempty"`
+	Read   []byte    `json:"read,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
+
+func (f *Follower) trace(event string, read []byte, err error) {
+	if f.Trace == nil {
+		return
+	}
+
+	e := traceEvent{Time: time.Now(), Event: event, Read: read}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	// Not much we can do about errors here, and it's just for debugging.
+	_ = json.NewEncoder(f.Trace).Encode(e)
+}
+
+func (f *Follower) traceOpen(k EventKind) {
+	if f.Trace == nil {
+		return
+	}
+	_ = json.NewEncoder(f.Trace).Encode(traceEvent{Time: time.Now(), Event: "open", Kind: k.String(), File: f.name, Offset: f.offset})
+}
+
+// Get the EventKind for an "open" in a trace; traces recorded before "kind"
+// was added are always EventOpen.
+func openKind(kind string) EventKind {
+	for _, k := range []EventKind{EventReopen, EventReappear} {
+		if kind == k.String() {
+			return k
+		}
+	}
+	return EventOpen
+}
+
+// Replay a trace recorded with Follower.Trace.
+//
+// This sends the same data over the Data channel that Start sent when the
+// trace was recorded (and the same lifecycle events on Events, if set), without
+// touching the filesystem, and returns once the trace is fully read. The
+// options (MaxLineLen, Encoding, etc.) should be the same as when the trace was
+// recorded.
+//
+// A trace is a stream of JSON objects, one per line:
+//
+//	{"time": "2006-01-02T15:04:05Z", "event": "WRITE"}
+//	{"time": "2006-01-02T15:04:05Z", "event": "read", "read": "aGVsbG8K"}
+//
+// The event is the fsnotify operation ("WRITE", "REMOVE", etc.) or one of:
+//
+//	open       File was opened or reopened, with the name in "file", the
+//	           starting offset in "offset", and the event kind ("open",
+//	           "reopen", or "reappear") in "kind".
+//	truncate   File was truncated.
+//	read       Data was read from the file, as base64 in "read".
+//	error      Error from the watcher or reading, as a string in "error".
+func (f *Follower) Replay(ctx context.Context, trace io.Reader) error {
+	close(f.Ready)
+	f.stopOnCancel(ctx)
+	defer func() {
+		f.Stop()
+		f.sendFinal(Data{Err: io.EOF})
+	}()
+
+	scan := bufio.NewScanner(trace)
+	scan.Buffer(nil, 1<<30)
+	for i := 1; scan.Scan(); i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var e traceEvent
+		err := json.Unmarshal(scan.Bytes(), &e)
+		if err != nil {
+			return fmt.Errorf("follow.Replay: line %d: %w", i, err)
+		}
+
+		switch e.Event {
+		case "REMOVE":
+			f.event(EventRemove)
+		case "RENAME":
+			f.event(EventRotate)
+		case "open":
+			f.fpMu.Lock()
+			f.reset()
+			f.name, f.offset = e.File, e.Offset
+			f.event(openKind(e.Kind))
+			f.fpMu.Unlock()
+		case "truncate":
+			f.fpMu.Lock()
+			f.reset()
+			f.event(EventTruncate)
+			f.fpMu.Unlock()
+		case "read":
+			if e.Error != "" {
+				f.send(Data{Err: fmt.Errorf("replay: %s", e.Error)})
+			}
+			f.fpMu.Lock()
+			f.readAt = e.Time
+			lines := f.process(e.Read)
+			f.fpMu.Unlock()
+
+			f.sendAll(lines)
+		case "error":
+			f.send(Data{Err: fmt.Errorf("replay: %s", e.Error)})
+		}
+		f.sendEvents()
+	}
+	f.sendMultiline()
+	return scan.Err()
+}
@@ -0,0 +1,133 @@
+package follow
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type lockedBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func TestReplay(t *testing.T) {
+	trace := new(lockedBuffer)
+	f, tmp, lines := startWith(context.Background(), t, func(f *Follower) {
+		f.Trace = trace
+		f.MaxLineLen = 5
+	})
+	want := write(t, tmp, "one", "two")
+	appendString(t, tmp, "thr")
+	write(t, tmp, "ee")
+	want = append(want, "three")
+	err := os.Truncate(tmp, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = append(want, write(t, tmp, "a")...)
+	write(t, tmp, "longer line")
+	want = append(want, "longe")
+
+	f.Stop()
+	got := <-lines
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("\ngot:  %q\nwant: %q", got, want)
+	}
+	if !strings.Contains(trace.String(), `"event":"truncate"`) {
+		t.Errorf("no truncate in trace:\n%s", trace)
+	}
+
+	r := New()
+	r.MaxLineLen = 5
+	go func() {
+		err := r.Replay(context.Background(), strings.NewReader(trace.String()))
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+	var replayed []string
+	for d := range r.Data {
+		if d.Err == io.EOF {
+			break
+		}
+		if d.Err != nil {
+			t.Fatal(d.Err)
+		}
+		replayed = append(replayed, d.String())
+	}
+	if !reflect.DeepEqual(replayed, want) {
+		t.Errorf("\ngot:  %q\nwant: %q", replayed, want)
+	}
+}
+
+// Replay sends the same lifecycle events, including reopens and reappears.
+func TestReplayEvents(t *testing.T) {
+	trace := new(lockedBuffer)
+	events := make(chan Event, 10)
+	f, tmp, lines := startWith(context.Background(), t, func(f *Follower) {
+		f.Trace = trace
+		f.Events = events
+	})
+	write(t, tmp, "one")
+	err := os.Remove(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	touch(t, tmp)
+	time.Sleep(50 * time.Millisecond)
+	write(t, tmp, "two")
+	err = f.ReopenFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.Stop()
+	<-lines
+	kinds := func(events chan Event) []EventKind {
+		var k []EventKind
+		for len(events) > 0 {
+			k = append(k, (<-events).Kind)
+		}
+		return k
+	}
+	want := kinds(events)
+	if w := []EventKind{EventOpen, EventRemove, EventReappear, EventReopen}; !reflect.DeepEqual(want, w) {
+		t.Fatalf("\ngot:  %v\nwant: %v", want, w)
+	}
+
+	r := New()
+	r.Events = make(chan Event, 10)
+	go func() {
+		err := r.Replay(context.Background(), strings.NewReader(trace.String()))
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+	for d := range r.Data {
+		if d.Err == io.EOF {
+			break
+		}
+	}
+	if got := kinds(r.Events); !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %v\nwant: %v", got, want)
+	}
+}
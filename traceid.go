@@ -0,0 +1,37 @@
+package follow
+
+import "regexp"
+
+// TraceIDField returns a function for Follower.TraceID that gets the trace ID
+// from the first of the fields that's set in Record.Fields, for example:
+//
+//	f.Parser = follow.Logfmt{}
+//	f.TraceID = follow.TraceIDField("trace_id", "traceID")
+func TraceIDField(fields ...string) func(Data) string {
+	return func(d Data) string {
+		for _, k := range fields {
+			if v, ok := d.Fields[k]; ok {
+				return v
+			}
+		}
+		return ""
+	}
+}
+
+// TraceIDRegexp returns a function for Follower.TraceID that gets the trace ID
+// from the first submatch of the regexp, or the full match if there are no
+// groups. For example, for a W3C traceparent:
+//
+//	f.TraceID = follow.TraceIDRegexp(regexp.MustCompile(`traceparent=00-([0-9a-f]{32})-`))
+func TraceIDRegexp(re *regexp.Regexp) func(Data) string {
+	return func(d Data) string {
+		m := re.FindSubmatch(d.Bytes)
+		switch {
+		case m == nil:
+			return ""
+		case len(m) > 1:
+			return string(m[1])
+		}
+		return string(m[0])
+	}
+}
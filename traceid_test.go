@@ -0,0 +1,60 @@
+package follow
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestTraceID(t *testing.T) {
+	t.Run("field", func(t *testing.T) {
+		f, tmp, data := startData(context.Background(), t, func(f *Follower) {
+			f.Parser = Logfmt{}
+			f.TraceID = TraceIDField("trace_id", "traceID")
+		})
+		write(t, tmp, "msg=a trace_id=1", "msg=b traceID=2", "msg=c")
+
+		f.Stop()
+		var got []string
+		for _, d := range <-data {
+			got = append(got, d.TraceID)
+		}
+		want := []string{"1", "2", ""}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+
+	// The ID is taken from the line as it was read, before Transforms.
+	t.Run("transform", func(t *testing.T) {
+		re := regexp.MustCompile(`req-[0-9]+`)
+		f, tmp, data := startData(context.Background(), t, func(f *Follower) {
+			f.TraceID = TraceIDRegexp(re)
+			f.Transforms = []Transform{Redact(re, "req-x")}
+		})
+		write(t, tmp, "GET / req-42 200")
+
+		f.Stop()
+		got := <-data
+		if len(got) != 1 || got[0].TraceID != "req-42" || got[0].String() != "GET / req-x 200" {
+			t.Errorf("wrong data: %+v", got)
+		}
+	})
+
+	t.Run("regexp", func(t *testing.T) {
+		tests := []struct {
+			re, in, want string
+		}{
+			{`req-[0-9]+`, "GET / req-42 200", "req-42"},
+			{`id=(\w+)`, "id=abc x", "abc"},
+			{`id=(\w+)`, "nope", ""},
+		}
+		for _, tt := range tests {
+			got := TraceIDRegexp(regexp.MustCompile(tt.re))(Data{Bytes: []byte(tt.in)})
+			if got != tt.want {
+				t.Errorf("%q on %q: got %q; want %q", tt.re, tt.in, got, tt.want)
+			}
+		}
+	})
+}
@@ -0,0 +1,29 @@
+package follow
+
+// Transform changes or drops a line before it's sent; return false to drop the
+// line.
+//
+// For example, to add the hostname to every line:
+//
+//	host, _ := os.Hostname()
+//	f.Transforms = append(f.Transforms, func(d follow.Data) (follow.Data, bool) {
+//		d.Bytes = append([]byte(host+" "), d.Bytes...)
+//		return d, true
+//	})
+//
+// Transforms are called from the goroutine that reads the file, so they should
+// return quickly. Don't modify d.Bytes in place if Follower.Pool is set; make a
+// new slice instead.
+type Transform func(Data) (Data, bool)
+
+// Run all transforms, stopping at the first one that drops the line.
+func (f *Follower) transform(d *Data) bool {
+	for _, t := range f.Transforms {
+		var ok bool
+		*d, ok = t(*d)
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
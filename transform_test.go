@@ -0,0 +1,33 @@
+package follow
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestTransforms(t *testing.T) {
+	var seen []string
+	f, tmp, lines := startWith(context.Background(), t, func(f *Follower) {
+		f.Transforms = []Transform{
+			func(d Data) (Data, bool) {
+				return d, !bytes.Contains(d.Bytes, []byte("drop"))
+			},
+			func(d Data) (Data, bool) {
+				seen = append(seen, d.String())
+				d.Bytes = bytes.ToUpper(d.Bytes)
+				return d, true
+			},
+		}
+	})
+	write(t, tmp, "one", "drop me", "two")
+	f.Stop()
+
+	if got, want := <-lines, []string{"ONE", "TWO"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+	if want := []string{"one", "two"}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("second transform saw %q; want %q", seen, want)
+	}
+}
@@ -0,0 +1,84 @@
+package follow
+
+import (
+	"context"
+	"io"
+)
+
+type exit struct {
+	done chan struct{} // Closed when Start returns.
+	err  error         // Returned by Start; only set after done is closed.
+}
+
+// Go starts following a file in the background.
+//
+// This returns once the file is opened and watched, or an error if that
+// failed. Use Wait, Done, and Err to get the error Start returned.
+func (f *Follower) Go(ctx context.Context, file string) error {
+	go f.Start(ctx, file)
+	select {
+	case <-f.Ready:
+		return nil
+	case <-f.exit.done:
+		return f.exit.err
+	}
+}
+
+// Wait until Start returns, and return its error.
+func (f *Follower) Wait() error {
+	<-f.exit.done
+	return f.exit.err
+}
+
+// Done returns a channel that's closed when Start returns.
+func (f *Follower) Done() <-chan struct{} { return f.exit.done }
+
+// Err returns the error Start returned, or nil if it's still running.
+func (f *Follower) Err() error {
+	select {
+	case <-f.exit.done:
+		return f.exit.err
+	default:
+		return nil
+	}
+}
+
+// Each follows the file and calls fn for every line, until fn returns an error
+// or the context is cancelled.
+//
+// The Data passed to fn may have Err set; io.EOF is never passed. This returns
+// the error from fn or the context.
+func Each(ctx context.Context, path string, fn func(Data) error) error {
+	f := New()
+	err := f.Go(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	for {
+		var d Data
+		select {
+		case <-ctx.Done():
+			f.stopAndDrain()
+			return ctx.Err()
+		case d = <-f.Data:
+		}
+		if d.Err == io.EOF {
+			return f.Wait()
+		}
+		if err := fn(d); err != nil {
+			f.stopAndDrain()
+			return err
+		}
+	}
+}
+
+// Stop, and read from Data until io.EOF so that Start can return.
+func (f *Follower) stopAndDrain() {
+	f.Stop()
+	for d := range f.Data {
+		if d.Err == io.EOF {
+			return
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package follow
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGo(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		tmp := filepath.Join(t.TempDir(), "f")
+		touch(t, tmp)
+
+		f := New()
+		err := f.Go(context.Background(), tmp)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		write(t, tmp, "line")
+		if d := <-f.Data; d.String() != "line" {
+			t.Errorf("wrong line: %q", d)
+		}
+
+		select {
+		case <-f.Done():
+			t.Fatal("Done closed")
+		default:
+		}
+		if err := f.Err(); err != nil {
+			t.Fatal(err)
+		}
+
+		f.Stop()
+		if d := <-f.Data; d.Err != io.EOF {
+			t.Errorf("wrong error: %v", d.Err)
+		}
+		if err := f.Wait(); err != nil {
+			t.Fatal(err)
+		}
+		<-f.Done()
+	})
+
+	t.Run("error", func(t *testing.T) {
+		f := New()
+		err := f.Go(context.Background(), filepath.Join(t.TempDir(), "doesnt-exist"))
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("wrong error: %v", err)
+		}
+		if err := f.Wait(); !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("wrong error: %v", err)
+		}
+		if err := f.Err(); !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("wrong error: %v", err)
+		}
+	})
+}
+
+func TestEach(t *testing.T) {
+	tmp := filepath.Join(t.TempDir(), "f")
+	touch(t, tmp)
+
+	var (
+		stop = errors.New("stop")
+		got  []string
+		done = make(chan error)
+	)
+	go func() {
+		done <- Each(context.Background(), tmp, func(d Data) error {
+			got = append(got, d.String())
+			if len(got) == 2 {
+				return stop
+			}
+			return nil
+		})
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	write(t, tmp, "one", "two", "three")
+	if err := <-done; err != stop {
+		t.Fatalf("wrong error: %v", err)
+	}
+	if want := []string{"one", "two"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot:  %q\nwant: %q", got, want)
+	}
+}
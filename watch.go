@@ -0,0 +1,70 @@
+package follow
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch controls how the file is watched for changes.
+type Watch uint8
+
+const (
+	// Watch the directory with fsnotify; this works everywhere, and also sees
+	// another file being renamed over the file.
+	WatchDir Watch = iota
+
+	// Watch just the file, which gives less event noise in busy directories.
+	// This uses inotify on Linux and kqueue on the BSDs and macOS, and is the
+	// same as WatchDir on other systems.
+	//
+	// On Linux this also sets Event.RenamedTo for EventRotate.
+	WatchFile
+)
+
+// watcher sends filesystem events for the file.
+type watcher interface {
+	Events() <-chan fsnotify.Event
+	Errors() <-chan error
+	Close() error
+
+	// Watch the file after it was opened or reopened.
+	opened(fp *os.File) error
+
+	// Watch the directory again after it came back.
+	rewatch(dir string) error
+
+	// New name of the file after a Rename event, if known.
+	renamedTo() string
+}
+
+func (f *Follower) newWatcher() (watcher, error) {
+	if f.Watch == WatchFile {
+		if w, ok, err := newFileWatcher(f.file); ok {
+			return w, err
+		}
+	}
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// Watch the directory rather than the file; there doesn't seem to be any
+	// event sent when removing a file (on my Linux system, anyway).
+	err = w.Add(filepath.Dir(f.file))
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	return dirWatcher{w}, nil
+}
+
+type dirWatcher struct{ w *fsnotify.Watcher }
+
+func (w dirWatcher) Events() <-chan fsnotify.Event { return w.w.Events }
+func (w dirWatcher) Errors() <-chan error          { return w.w.Errors }
+func (w dirWatcher) Close() error                  { return w.w.Close() }
+func (w dirWatcher) opened(*os.File) error         { return nil }
+func (w dirWatcher) rewatch(dir string) error      { return w.w.Add(dir) }
+func (w dirWatcher) renamedTo() string             { return "" }
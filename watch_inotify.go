@@ -0,0 +1,205 @@
+//go:build linux
+
+package follow
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"unsafe"
+
+	"github.com/fsnotify/fsnotify"
+	"golang.org/x/sys/unix"
+)
+
+// Watch the file with inotify, for WatchFile.
+//
+// The file is watched for writes, and the directory only for renames; writes
+// to other files in the directory don't send any events. The rename cookies
+// tell us where the file was renamed to, and if another file was renamed over
+// it.
+type inotifyWatcher struct {
+	file, dir string
+	fd        int
+	fp        *os.File // For fd, so that Close stops a blocking Read.
+	events    chan fsnotify.Event
+	errors    chan error
+	done      chan struct{}
+	once      sync.Once
+
+	mu      sync.Mutex
+	fileWd  int32
+	dirWd   int32
+	cur     os.FileInfo // File that fileWd is watching.
+	gone    bool        // Sent Remove or Rename for cur.
+	cookie  uint32      // Cookie of the last IN_MOVED_FROM for the file.
+	renamed string      // Where cur was renamed to.
+}
+
+const (
+	inotifyFile = unix.IN_MODIFY | unix.IN_ATTRIB | unix.IN_MOVE_SELF | unix.IN_DELETE_SELF
+	inotifyDir  = unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_MOVE_SELF | unix.IN_DELETE_SELF | unix.IN_ONLYDIR
+)
+
+func newFileWatcher(name string) (watcher, bool, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, true, os.NewSyscallError("inotify_init1", err)
+	}
+	w := &inotifyWatcher{
+		file:   name,
+		dir:    filepath.Dir(name),
+		fd:     fd,
+		fp:     os.NewFile(uintptr(fd), "inotify"),
+		events: make(chan fsnotify.Event),
+		errors: make(chan error),
+		done:   make(chan struct{}),
+		fileWd: -1,
+	}
+	err = w.rewatch(w.dir)
+	if err != nil {
+		w.fp.Close()
+		return nil, true, err
+	}
+	go w.loop()
+	return w, true, nil
+}
+
+func (w *inotifyWatcher) Events() <-chan fsnotify.Event { return w.events }
+func (w *inotifyWatcher) Errors() <-chan error          { return w.errors }
+
+func (w *inotifyWatcher) Close() error {
+	w.once.Do(func() {
+		close(w.done)
+		w.fp.Close()
+	})
+	return nil
+}
+
+func (w *inotifyWatcher) rewatch(dir string) error {
+	wd, err := unix.InotifyAddWatch(w.fd, dir, inotifyDir)
+	if err != nil {
+		return os.NewSyscallError("inotify_add_watch", err)
+	}
+	w.mu.Lock()
+	w.dirWd = int32(wd)
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *inotifyWatcher) opened(fp *os.File) error {
+	st, err := fp.Stat()
+	if err != nil {
+		return err
+	}
+	wd, err := unix.InotifyAddWatch(w.fd, w.file, inotifyFile)
+	if err != nil {
+		return os.NewSyscallError("inotify_add_watch", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	// The old file may still be around, for example after a rename. This
+	// fails if it was removed, which is fine.
+	if w.fileWd != -1 && w.fileWd != int32(wd) {
+		_, _ = unix.InotifyRmWatch(w.fd, uint32(w.fileWd))
+	}
+	w.fileWd, w.cur, w.gone, w.renamed = int32(wd), st, false, ""
+	return nil
+}
+
+func (w *inotifyWatcher) renamedTo() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.renamed
+}
+
+func (w *inotifyWatcher) loop() {
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := w.fp.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				select {
+				case w.errors <- err:
+				case <-w.done:
+				}
+			}
+			return
+		}
+
+		for off := 0; off+unix.SizeofInotifyEvent <= n; {
+			raw := (*unix.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			start := off + unix.SizeofInotifyEvent
+			off = start + int(raw.Len)
+			name := string(bytes.TrimRight(buf[start:off], "\x00"))
+
+			if raw.Mask&unix.IN_Q_OVERFLOW != 0 {
+				select {
+				case w.errors <- fsnotify.ErrEventOverflow:
+				case <-w.done:
+					return
+				}
+				continue
+			}
+			if e, ok := w.event(raw.Wd, raw.Mask, raw.Cookie, name); ok {
+				select {
+				case w.events <- e:
+				case <-w.done:
+					return
+				}
+			}
+		}
+	}
+}
+
+// Translate an inotify event; ok is false if it should be ignored.
+func (w *inotifyWatcher) event(wd int32, mask, cookie uint32, name string) (e fsnotify.Event, ok bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	switch wd {
+	case w.dirWd:
+		switch {
+		case mask&unix.IN_DELETE_SELF != 0:
+			return fsnotify.Event{Name: w.dir, Op: fsnotify.Remove}, true
+		case mask&unix.IN_MOVE_SELF != 0:
+			return fsnotify.Event{Name: w.dir, Op: fsnotify.Rename}, true
+		case mask&unix.IN_MOVED_TO != 0 && cookie == w.cookie:
+			w.renamed = filepath.Join(w.dir, name)
+		case name != filepath.Base(w.file):
+		case mask&unix.IN_MOVED_FROM != 0:
+			w.cookie = cookie
+		case mask&unix.IN_MOVED_TO != 0 && !w.gone:
+			// Another file was renamed over it.
+			w.gone = true
+			return fsnotify.Event{Name: w.file, Op: fsnotify.Rename}, true
+		}
+
+	case w.fileWd:
+		if w.gone {
+			break
+		}
+		switch {
+		case mask&unix.IN_MOVE_SELF != 0:
+			w.gone = true
+			return fsnotify.Event{Name: w.file, Op: fsnotify.Rename}, true
+		case mask&unix.IN_DELETE_SELF != 0:
+			w.gone = true
+			return fsnotify.Event{Name: w.file, Op: fsnotify.Remove}, true
+		case mask&unix.IN_ATTRIB != 0:
+			// Removing the file only changes the link count, as we still
+			// have it open; IN_DELETE_SELF is sent once we close it.
+			if st, err := os.Stat(w.file); err == nil && os.SameFile(st, w.cur) {
+				break
+			}
+			w.gone = true
+			return fsnotify.Event{Name: w.file, Op: fsnotify.Remove}, true
+		case mask&unix.IN_MODIFY != 0:
+			return fsnotify.Event{Name: w.file, Op: fsnotify.Write}, true
+		}
+	}
+	return e, false
+}
@@ -0,0 +1,128 @@
+//go:build freebsd || openbsd || netbsd || dragonfly || darwin
+
+package follow
+
+import (
+	"os"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"golang.org/x/sys/unix"
+)
+
+// Watch the file's vnode with kqueue, for WatchFile.
+//
+// The watch is removed when the file is closed, so this needs to be called
+// again with opened() after every reopen. There are no events for the
+// directory, but we don't need them: the vnode gets NOTE_DELETE if the file is
+// removed, either directly, with the directory, or by renaming another file
+// over it.
+type kqueueWatcher struct {
+	name   string
+	kq     int
+	wake   [2]int // Pipe to wake up kevent() on Close.
+	events chan fsnotify.Event
+	errors chan error
+	done   chan struct{}
+	once   sync.Once
+}
+
+func newFileWatcher(name string) (watcher, bool, error) {
+	kq, err := unix.Kqueue()
+	if err != nil {
+		return nil, true, os.NewSyscallError("kqueue", err)
+	}
+	w := &kqueueWatcher{
+		name:   name,
+		kq:     kq,
+		events: make(chan fsnotify.Event),
+		errors: make(chan error),
+		done:   make(chan struct{}),
+	}
+	err = unix.Pipe(w.wake[:])
+	if err != nil {
+		unix.Close(kq)
+		return nil, true, os.NewSyscallError("pipe", err)
+	}
+	err = w.register(w.wake[0], unix.EVFILT_READ, 0)
+	if err != nil {
+		unix.Close(kq)
+		unix.Close(w.wake[0])
+		unix.Close(w.wake[1])
+		return nil, true, err
+	}
+	go w.loop()
+	return w, true, nil
+}
+
+func (w *kqueueWatcher) Events() <-chan fsnotify.Event { return w.events }
+func (w *kqueueWatcher) Errors() <-chan error          { return w.errors }
+func (w *kqueueWatcher) rewatch(string) error          { return nil }
+func (w *kqueueWatcher) renamedTo() string             { return "" }
+
+func (w *kqueueWatcher) Close() error {
+	w.once.Do(func() {
+		close(w.done)
+		unix.Close(w.wake[1])
+	})
+	return nil
+}
+
+func (w *kqueueWatcher) opened(fp *os.File) error {
+	return w.register(int(fp.Fd()), unix.EVFILT_VNODE,
+		unix.NOTE_WRITE|unix.NOTE_EXTEND|unix.NOTE_ATTRIB|unix.NOTE_DELETE|unix.NOTE_RENAME)
+}
+
+func (w *kqueueWatcher) register(fd, filter int, fflags uint32) error {
+	var k unix.Kevent_t
+	unix.SetKevent(&k, fd, filter, unix.EV_ADD|unix.EV_CLEAR)
+	k.Fflags = fflags
+	_, err := unix.Kevent(w.kq, []unix.Kevent_t{k}, nil, nil)
+	return os.NewSyscallError("kevent", err)
+}
+
+func (w *kqueueWatcher) loop() {
+	defer func() {
+		unix.Close(w.kq)
+		unix.Close(w.wake[0])
+	}()
+
+	buf := make([]unix.Kevent_t, 16)
+	for {
+		n, err := unix.Kevent(w.kq, nil, buf, nil)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			select {
+			case w.errors <- os.NewSyscallError("kevent", err):
+			case <-w.done:
+			}
+			return
+		}
+
+		for _, k := range buf[:n] {
+			if int(k.Ident) == w.wake[0] {
+				return
+			}
+
+			var op fsnotify.Op
+			if k.Fflags&unix.NOTE_DELETE != 0 {
+				op |= fsnotify.Remove
+			}
+			if k.Fflags&unix.NOTE_RENAME != 0 {
+				op |= fsnotify.Rename
+			}
+			// Truncating only sets NOTE_ATTRIB; send a write so that it's
+			// read, which detects the truncation.
+			if k.Fflags&(unix.NOTE_WRITE|unix.NOTE_EXTEND|unix.NOTE_ATTRIB) != 0 {
+				op |= fsnotify.Write
+			}
+			select {
+			case w.events <- fsnotify.Event{Name: w.name, Op: op}:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,6 @@
+//go:build !linux && !freebsd && !openbsd && !netbsd && !dragonfly && !darwin
+
+package follow
+
+// No way to watch just the file on this system; use WatchDir.
+func newFileWatcher(string) (watcher, bool, error) { return nil, false, nil }
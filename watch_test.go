@@ -0,0 +1,73 @@
+package follow
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	for name, watch := range map[string]Watch{"dir": WatchDir, "file": WatchFile} {
+		t.Run(name, func(t *testing.T) {
+			var events chan Event
+			f, tmp, lines := startWith(context.Background(), t, func(f *Follower) {
+				events = make(chan Event, 10)
+				f.Events = events
+				f.Watch = watch
+			})
+
+			want := write(t, tmp, "before")
+			err := os.Truncate(tmp, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(10 * time.Millisecond)
+			want = append(want, write(t, tmp, "truncated")...)
+
+			err = os.Rename(tmp, tmp+".1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			touch(t, tmp)
+			time.Sleep(50 * time.Millisecond)
+			want = append(want, write(t, tmp, "rotated")...)
+
+			err = os.Remove(tmp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			touch(t, tmp)
+			time.Sleep(50 * time.Millisecond)
+			want = append(want, write(t, tmp, "removed")...)
+
+			f.Stop()
+			if got := <-lines; !reflect.DeepEqual(got, want) {
+				t.Errorf("\ngot:  %q\nwant: %q", got, want)
+			}
+
+			var got []EventKind
+			for len(events) > 0 {
+				e := <-events
+				got = append(got, e.Kind)
+				if e.Kind != EventRotate {
+					continue
+				}
+				wantTo := ""
+				if watch == WatchFile && runtime.GOOS == "linux" {
+					wantTo = tmp + ".1"
+				}
+				if e.RenamedTo != wantTo {
+					t.Errorf("RenamedTo is %q; want %q", e.RenamedTo, wantTo)
+				}
+			}
+			wantEv := []EventKind{EventOpen, EventTruncate, EventRotate, EventReappear,
+				EventRemove, EventReappear}
+			if !reflect.DeepEqual(got, wantEv) {
+				t.Errorf("\ngot:  %v\nwant: %v", got, wantEv)
+			}
+		})
+	}
+}
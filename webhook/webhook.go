@@ -0,0 +1,244 @@
+// Package webhook sends followed lines to an HTTP endpoint.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"text/template"
+	"time"
+
+	"zgo.at/follow"
+)
+
+// DefaultBody is the default body template, which sends the lines as JSON:
+//
+//	{"lines": ["line 1", "line 2"]}
+var DefaultBody = template.Must(template.New("").Funcs(Funcs).Parse(
+	`{"lines": [{{range $i, $l := .}}{{if $i}}, {{end}}{{json $l.String}}{{end}}]}`))
+
+// Funcs are the functions available in body templates.
+//
+//	json   JSON-encode the value.
+var Funcs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		j, err := json.Marshal(v)
+		return string(j), err
+	},
+}
+
+// Webhook POSTs lines to an endpoint.
+//
+// Fields that are zero use their default, except Retries, where 0 means
+// requests are never retried. New sets all the defaults.
+type Webhook struct {
+	URL string
+
+	// Template for the request body; this gets a []follow.Data. Use
+	// template.New("").Funcs(webhook.Funcs) to get the "json" function.
+	//
+	// Default is DefaultBody.
+	Body *template.Template
+
+	// Headers to add to every request; the Content-Type is application/json
+	// unless set here.
+	Header http.Header
+
+	// Sign the body with HMAC-SHA256 using this secret, which is sent as
+	// "sha256=<hex>" in the X-Signature header.
+	//
+	// Default is nil, which means requests aren't signed.
+	Secret []byte
+
+	// Number of times to retry on errors and 5xx or 429 responses, waiting
+	// RetryWait between the first attempts and doubling it every time.
+	//
+	// Default is 3 retries, starting at 1 second.
+	Retries   int
+	RetryWait time.Duration
+
+	// Send at most this many lines in one request in Run.
+	//
+	// Default is 100.
+	BatchSize int
+
+	// Wait for at most this long before sending a batch in Run.
+	//
+	// Default is 1 second.
+	BatchWait time.Duration
+
+	// Client to use; default is http.DefaultClient.
+	Client *http.Client
+}
+
+// New creates a new webhook with the default settings.
+func New(url string) *Webhook {
+	return &Webhook{
+		URL:       url,
+		Body:      DefaultBody,
+		Header:    make(http.Header),
+		Retries:   3,
+		RetryWait: time.Second,
+		BatchSize: 100,
+		BatchWait: time.Second,
+		Client:    http.DefaultClient,
+	}
+}
+
+// Copy of w with the defaults for fields that are zero, so a Webhook that
+// wasn't created with New works too.
+func (w *Webhook) withDefaults() *Webhook {
+	c := *w
+	if c.Body == nil {
+		c.Body = DefaultBody
+	}
+	if c.RetryWait <= 0 {
+		c.RetryWait = time.Second
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 100
+	}
+	if c.BatchWait <= 0 {
+		c.BatchWait = time.Second
+	}
+	if c.Client == nil {
+		c.Client = http.DefaultClient
+	}
+	return &c
+}
+
+// Send lines to the endpoint, retrying on errors.
+func (w *Webhook) Send(ctx context.Context, lines ...follow.Data) error {
+	w = w.withDefaults()
+	body := new(bytes.Buffer)
+	err := w.Body.Execute(body, lines)
+	if err != nil {
+		return fmt.Errorf("webhook.Send: %w", err)
+	}
+
+	wait := w.RetryWait
+	for i := 0; ; i++ {
+		retry, err := w.post(ctx, body.Bytes())
+		if err == nil {
+			return nil
+		}
+		if !retry || i >= w.Retries {
+			return fmt.Errorf("webhook.Send: %w", err)
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return fmt.Errorf("webhook.Send: %w", ctx.Err())
+		case <-t.C:
+		}
+		wait *= 2
+	}
+}
+
+func (w *Webhook) post(ctx context.Context, body []byte) (bool, error) {
+	r, err := http.NewRequestWithContext(ctx, "POST", w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for k, v := range w.Header {
+		r.Header[k] = v
+	}
+	if r.Header.Get("Content-Type") == "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	if w.Secret != nil {
+		r.Header.Set("X-Signature", "sha256="+Sign(w.Secret, body))
+	}
+
+	resp, err := w.Client.Do(r)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("%s: %s", w.URL, resp.Status)
+	}
+	return false, nil
+}
+
+// Alert returns a function to send a single line, for use with follow.Alert.
+//
+// The line is sent in the background so it doesn't block reading the file;
+// errors are logged with the standard log package.
+func (w *Webhook) Alert() func(follow.Data) {
+	return func(d follow.Data) {
+		d.Bytes = append([]byte(nil), d.Bytes...)
+		go func() {
+			err := w.Send(context.Background(), d)
+			if err != nil {
+				log.Print(err)
+			}
+		}()
+	}
+}
+
+// Run sends all lines from data in batches, until io.EOF is received, the
+// channel is closed, or the context is cancelled.
+//
+// Data with an error other than io.EOF is skipped. This returns the first error
+// from sending a batch.
+func (w *Webhook) Run(ctx context.Context, data <-chan follow.Data) error {
+	w = w.withDefaults()
+	var (
+		batch = make([]follow.Data, 0, w.BatchSize)
+		t     = time.NewTicker(w.BatchWait)
+	)
+	defer t.Stop()
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := w.Send(ctx, batch...)
+		batch = batch[:0]
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			if err := flush(); err != nil {
+				return err
+			}
+		case d, ok := <-data:
+			if !ok || d.Err == io.EOF {
+				return flush()
+			}
+			if d.Err != nil {
+				continue
+			}
+			batch = append(batch, d)
+			if len(batch) >= w.BatchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// Sign the body with HMAC-SHA256, returning the hex-encoded signature.
+func Sign(secret, body []byte) string {
+	h := hmac.New(sha256.New, secret)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,151 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"zgo.at/follow"
+)
+
+func TestSend(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		bodies []string
+		fail   = 1
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if fail > 0 {
+			fail--
+			w.WriteHeader(500)
+			return
+		}
+
+		b, _ := io.ReadAll(r.Body)
+		if sig := r.Header.Get("X-Signature"); sig != "sha256="+Sign([]byte("secret"), b) {
+			t.Errorf("wrong signature: %q", sig)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("wrong content-type: %q", ct)
+		}
+		if h := r.Header.Get("X-Test"); h != "yes" {
+			t.Errorf("wrong header: %q", h)
+		}
+		bodies = append(bodies, string(b))
+	}))
+	defer srv.Close()
+
+	w := New(srv.URL)
+	w.Secret = []byte("secret")
+	w.Header.Set("X-Test", "yes")
+	w.RetryWait = time.Millisecond
+
+	err := w.Send(context.Background(), follow.Data{Bytes: []byte(`a "quoted" line`)}, follow.Data{Bytes: []byte("two")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"lines": ["a \"quoted\" line", "two"]}`
+	if len(bodies) != 1 || bodies[0] != want {
+		t.Errorf("\ngot:  %q\nwant: %q", bodies, want)
+	}
+}
+
+func TestSendNoRetry(t *testing.T) {
+	n := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		w.WriteHeader(400)
+	}))
+	defer srv.Close()
+
+	w := New(srv.URL)
+	w.RetryWait = time.Millisecond
+	err := w.Send(context.Background(), follow.Data{Bytes: []byte("x")})
+	if err == nil {
+		t.Fatal("err is nil")
+	}
+	if n != 1 {
+		t.Errorf("sent %d requests", n)
+	}
+}
+
+func TestRun(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		bodies []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+	}))
+	defer srv.Close()
+
+	w := New(srv.URL)
+	w.BatchSize = 2
+
+	data := make(chan follow.Data)
+	go func() {
+		for _, l := range []string{"1", "2", "3"} {
+			data <- follow.Data{Bytes: []byte(l)}
+		}
+		data <- follow.Data{Err: io.EOF}
+	}()
+
+	err := w.Run(context.Background(), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{`{"lines": ["1", "2"]}`, `{"lines": ["3"]}`}
+	if len(bodies) != 2 || bodies[0] != want[0] || bodies[1] != want[1] {
+		t.Errorf("\ngot:  %q\nwant: %q", bodies, want)
+	}
+}
+
+// A Webhook that wasn't created with New uses the defaults.
+func TestZero(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		bodies []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+	}))
+	defer srv.Close()
+
+	w := &Webhook{URL: srv.URL}
+	err := w.Send(context.Background(), follow.Data{Bytes: []byte("1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := make(chan follow.Data, 10)
+	data <- follow.Data{Bytes: []byte("2")}
+	data <- follow.Data{Bytes: []byte("3")}
+	close(data)
+	err = w.Run(context.Background(), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{`{"lines": ["1"]}`, `{"lines": ["2", "3"]}`}
+	if len(bodies) != 2 || bodies[0] != want[0] || bodies[1] != want[1] {
+		t.Errorf("\ngot:  %q\nwant: %q", bodies, want)
+	}
+}